@@ -1,6 +1,8 @@
 module webcsv
 
-go 1.14
-
-require github.com/gorilla/mux v1.7.4
+go 1.17
 
+require (
+	github.com/gorilla/mux v1.7.4
+	golang.org/x/text v0.3.8
+)
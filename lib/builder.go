@@ -0,0 +1,103 @@
+package webcsv
+
+// SchemaBuilder - builds a schema in code
+//
+//	sch, err := webcsv.NewSchema("1.0").
+//		WithDelimiter(",").
+//		AddString("LastName", 50).
+//		AddInt("Age").
+//		AddDecimal("Height", 13, 3).
+//		Build()
+type SchemaBuilder struct {
+	schema *Schema
+}
+
+// NewSchema - starts building a schema of the version. The delimiter defaults to comma.
+func NewSchema(version string) *SchemaBuilder {
+	return &SchemaBuilder{
+		schema: &Schema{
+			Version:   version,
+			Delimiter: ",",
+		},
+	}
+}
+
+// WithDelimiter - sets the delimiter
+func (sb *SchemaBuilder) WithDelimiter(delimiter string) *SchemaBuilder {
+	sb.schema.Delimiter = delimiter
+	return sb
+}
+
+// WithHeader - sets if the data has a header
+func (sb *SchemaBuilder) WithHeader(withHeader bool) *SchemaBuilder {
+	sb.schema.WithHeader = withHeader
+	return sb
+}
+
+// AddColumn - adds a column as it is
+func (sb *SchemaBuilder) AddColumn(col SchemaColumn) *SchemaBuilder {
+	sb.schema.Columns = append(sb.schema.Columns, col)
+	return sb
+}
+
+// AddString - adds a string column with a maximum length
+func (sb *SchemaBuilder) AddString(name string, length int) *SchemaBuilder {
+	return sb.AddColumn(SchemaColumn{Name: name, Type: "string", Length: length})
+}
+
+// AddInt - adds an int column
+func (sb *SchemaBuilder) AddInt(name string) *SchemaBuilder {
+	return sb.AddColumn(SchemaColumn{Name: name, Type: "int"})
+}
+
+// AddDecimal - adds a decimal column with a precision and scale
+func (sb *SchemaBuilder) AddDecimal(name string, precision int, scale int) *SchemaBuilder {
+	return sb.AddColumn(SchemaColumn{Name: name, Type: "decimal", Precision: precision, Scale: scale})
+}
+
+// AddBool - adds a bool column
+func (sb *SchemaBuilder) AddBool(name string) *SchemaBuilder {
+	return sb.AddColumn(SchemaColumn{Name: name, Type: "bool"})
+}
+
+// AddDate - adds a date column
+func (sb *SchemaBuilder) AddDate(name string) *SchemaBuilder {
+	return sb.AddColumn(SchemaColumn{Name: name, Type: "date"})
+}
+
+// AddDateTime - adds a datetime column
+func (sb *SchemaBuilder) AddDateTime(name string) *SchemaBuilder {
+	return sb.AddColumn(SchemaColumn{Name: name, Type: "datetime"})
+}
+
+// AddTime - adds a time column
+func (sb *SchemaBuilder) AddTime(name string) *SchemaBuilder {
+	return sb.AddColumn(SchemaColumn{Name: name, Type: "time"})
+}
+
+// AddUUID - adds a uuid column
+func (sb *SchemaBuilder) AddUUID(name string) *SchemaBuilder {
+	return sb.AddColumn(SchemaColumn{Name: name, Type: "uuid"})
+}
+
+// AddEmail - adds an email column. A length of zero has no maximum length.
+func (sb *SchemaBuilder) AddEmail(name string, length int) *SchemaBuilder {
+	return sb.AddColumn(SchemaColumn{Name: name, Type: "email", Length: length})
+}
+
+// AddEnum - adds an enum column with the allowed values
+func (sb *SchemaBuilder) AddEnum(name string, choices ...string) *SchemaBuilder {
+	return sb.AddColumn(SchemaColumn{Name: name, Type: "enum", Choices: choices})
+}
+
+// Build - checks the schema by SelfCheck and returns it
+func (sb *SchemaBuilder) Build() (*Schema, error) {
+
+	if err := sb.schema.SelfCheck(); err != nil {
+		return nil, err
+	}
+
+	sb.schema.isloaded = true
+
+	return sb.schema, nil
+}
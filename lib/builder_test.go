@@ -0,0 +1,29 @@
+package webcsv
+
+import (
+	"testing"
+)
+
+func TestSchemaBuilder(t *testing.T) {
+	sch, err := NewSchema("1.0").
+		WithDelimiter("|").
+		WithHeader(true).
+		AddString("LastName", 50).
+		AddInt("Age").
+		AddDecimal("Height", 13, 3).
+		AddBool("Active").
+		AddEnum("Kind", "a", "b").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "ver:1.0,hdr:true,del:|; LastName:string(50),Age:int,Height:decimal(13,3),Active:bool,Kind:enum(a;b)"
+	if got := sch.PrintSchema(); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	if _, err = NewSchema("1.0").AddInt("Age").AddInt("Age").Build(); err == nil {
+		t.Fatal("got no error, want the repeated column rejected")
+	}
+}
@@ -0,0 +1,125 @@
+package webcsv
+
+import (
+	"container/list"
+	"regexp"
+	"sync"
+)
+
+// columnCache - data derived from the schema columns that is expensive to compute on every validation
+type columnCache struct {
+	sources     []string         // pattern of each column the cache was built from
+	patterns    []*regexp.Regexp // compiled pattern of each column
+	patternErrs []error          // error compiling the pattern of each column
+	layouts     []string         // time layout of each column
+}
+
+// current - checks if the cache was built from the columns as they are now
+func (cc *columnCache) current(cols []SchemaColumn) bool {
+	if len(cc.sources) != len(cols) {
+		return false
+	}
+
+	for cn := range cols {
+		if cc.sources[cn] != cols[cn].Pattern || cc.layouts[cn] != cols[cn].layout() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// compiled - returns the compiled column data. It is built on first use and kept for the next validations.
+// It is built again when the columns it comes from, like a pattern, are modified.
+func (sch *Schema) compiled() *columnCache {
+	sch.mu.Lock()
+	defer sch.mu.Unlock()
+
+	if sch.cache != nil && sch.cache.current(sch.Columns) {
+		return sch.cache
+	}
+
+	cc := &columnCache{
+		sources:     make([]string, len(sch.Columns)),
+		patterns:    make([]*regexp.Regexp, len(sch.Columns)),
+		patternErrs: make([]error, len(sch.Columns)),
+		layouts:     make([]string, len(sch.Columns)),
+	}
+
+	for cn := range sch.Columns {
+		sc := &sch.Columns[cn]
+		cc.sources[cn] = sc.Pattern
+		if sc.Pattern != "" {
+			cc.patterns[cn], cc.patternErrs[cn] = compilePattern(sc.Pattern)
+		}
+		cc.layouts[cn] = sc.layout()
+	}
+
+	sch.cache = cc
+
+	return cc
+}
+
+// ResetCache - discards the compiled column data. The data is also built again once the columns it comes
+// from are modified, so it is only needed to release the memory.
+func (sch *Schema) ResetCache() {
+	sch.mu.Lock()
+	sch.cache = nil
+	sch.mu.Unlock()
+}
+
+// SchemaCacheSize - maximum number of schemas kept by ParseSchemaCached
+const SchemaCacheSize = 128
+
+// schemaCache - least recently used cache of parsed schemas keyed by their raw string
+var schemaCache = struct {
+	sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // most recently used first
+}{
+	entries: make(map[string]*list.Element),
+	order:   list.New(),
+}
+
+type schemaCacheEntry struct {
+	raw    string
+	schema *Schema
+}
+
+// ParseSchemaCached - parses the schema like ParseSchema but keeps the parsed schema for the same raw string.
+// The same instance is returned for identical input, so it must be treated as read-only. Clone it to modify.
+func ParseSchemaCached(raw string) (*Schema, error) {
+
+	schemaCache.Lock()
+	if e, ok := schemaCache.entries[raw]; ok {
+		schemaCache.order.MoveToFront(e)
+		schemaCache.Unlock()
+		return e.Value.(*schemaCacheEntry).schema, nil
+	}
+	schemaCache.Unlock()
+
+	// Parse outside the lock. Errors are not cached.
+	sch, err := ParseSchema(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	schemaCache.Lock()
+	defer schemaCache.Unlock()
+
+	// another request could have parsed the same schema in the meantime
+	if e, ok := schemaCache.entries[raw]; ok {
+		schemaCache.order.MoveToFront(e)
+		return e.Value.(*schemaCacheEntry).schema, nil
+	}
+
+	schemaCache.entries[raw] = schemaCache.order.PushFront(&schemaCacheEntry{raw: raw, schema: sch})
+
+	if schemaCache.order.Len() > SchemaCacheSize {
+		last := schemaCache.order.Back()
+		schemaCache.order.Remove(last)
+		delete(schemaCache.entries, last.Value.(*schemaCacheEntry).raw)
+	}
+
+	return sch, nil
+}
@@ -0,0 +1,111 @@
+package webcsv
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCompiledModifiedColumns(t *testing.T) {
+	sch := qtySchema()
+	sch.Columns[0].Pattern = `[a-z]+`
+
+	if _, err := sch.ValidateReturn([]byte("ab,1\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the compiled pattern is not kept once the column changes
+	sch.Columns[0].Pattern = `[0-9]+`
+	if _, err := sch.ValidateReturn([]byte("ab,1\n")); err == nil {
+		t.Fatal("got no error, want the value rejected by the new pattern")
+	}
+
+	if _, err := sch.ValidateReturn([]byte("12,1\n")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// benchRecord - record of the benchmark schema. A small input shows the cost of compiling the schema.
+var benchRecord = []byte("AB-1234,12.50,2021-03-04\n")
+
+// benchSchema - returns a schema with a pattern and a date layout to compile
+func benchSchema(b *testing.B) *Schema {
+	sch, err := ParseSchema(`ver:1.0; Code:string(10)/[A-Z]{2}-\d{4}/,Amount:decimal(9,2),Day:date(2006-01-02)`)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return sch
+}
+
+func BenchmarkValidateCold(b *testing.B) {
+	sch := benchSchema(b)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		sch.ResetCache()
+		if _, err := sch.ValidateReturn(benchRecord); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkValidateWarm(b *testing.B) {
+	sch := benchSchema(b)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := sch.ValidateReturn(benchRecord); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestParseSchemaCached(t *testing.T) {
+	raw := "ver:1.0; A:string(5),B:int"
+
+	first, err := ParseSchemaCached(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := ParseSchemaCached(raw)
+	if err != nil || second != first {
+		t.Fatalf("got %p %v, want the cached instance %p", second, err, first)
+	}
+
+	if _, err = ParseSchemaCached("ver:1.0"); err == nil {
+		t.Fatal("got no error, want the invalid schema rejected")
+	}
+
+	// the least recently used schemas are dropped
+	for i := 0; i < SchemaCacheSize; i++ {
+		if _, err = ParseSchemaCached(fmt.Sprintf("ver:%d; A:int", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if third, _ := ParseSchemaCached(raw); third == first {
+		t.Fatal("got the dropped instance, want a new one")
+	}
+}
+
+func BenchmarkParseSchema(b *testing.B) {
+	raw := `ver:1.0,hdr:true; Code:string(10)/[A-Z]{2}-\d{4}/,Amount:decimal(9,2),Day:date(2006-01-02),Kind:enum(a;b;c)`
+
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := ParseSchema(raw); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := ParseSchemaCached(raw); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package webcsv
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// charsets - encodings of the input that can be transcoded to UTF-8, by their lower case names. UTF-8 has
+// no encoding, since it is read as it is. Other single byte charsets of x/text could be added here.
+var charsets = map[string]encoding.Encoding{
+	"":             nil,
+	"utf8":         nil,
+	"utf-8":        nil,
+	"latin1":       charmap.ISO8859_1,
+	"latin-1":      charmap.ISO8859_1,
+	"iso-8859-1":   charmap.ISO8859_1,
+	"iso8859-1":    charmap.ISO8859_1,
+	"latin9":       charmap.ISO8859_15,
+	"iso-8859-15":  charmap.ISO8859_15,
+	"iso8859-15":   charmap.ISO8859_15,
+	"cp1252":       charmap.Windows1252,
+	"windows-1252": charmap.Windows1252,
+}
+
+// charset - returns the encoding of the charset of the schema, or nil for UTF-8
+func (sch *Schema) charset() (encoding.Encoding, error) {
+	enc, ok := charsets[strings.ToLower(strings.TrimSpace(sch.Charset))]
+	if !ok {
+		return nil, fmt.Errorf("unknown charset %q", sch.Charset)
+	}
+	return enc, nil
+}
+
+// canonicalCharset - one lower case name of the charset of the schema, so the names of a charset (latin1,
+// iso-8859-1) print the same. UTF-8 is an empty string and unknown charsets keep their name.
+func (sch *Schema) canonicalCharset() string {
+	enc, err := sch.charset()
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(sch.Charset))
+	}
+
+	switch enc {
+	case charmap.ISO8859_1:
+		return "latin1"
+	case charmap.ISO8859_15:
+		return "latin9"
+	case charmap.Windows1252:
+		return "cp1252"
+	}
+	return ""
+}
+
+// decodeCharset - wraps the reader in a transcoder to UTF-8 for the charset of the schema. The byte order
+// mark of UTF-8 input is removed.
+func (sch *Schema) decodeCharset(rd io.Reader) (io.Reader, error) {
+	enc, err := sch.charset()
+	if err != nil {
+		return nil, err
+	}
+
+	if enc != nil {
+		return enc.NewDecoder().Reader(rd), nil
+	}
+
+	return stripBOM(rd), nil
+}
+
+// utf8BOM - byte order mark that Excel writes in front of UTF-8 files
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// stripBOM - skips the UTF-8 byte order mark at the start of the input. It would be part of the first value.
+func stripBOM(rd io.Reader) io.Reader {
+	br := bufio.NewReader(rd)
+	if b, err := br.Peek(len(utf8BOM)); err == nil && bytes.Equal(b, utf8BOM) {
+		br.Discard(len(utf8BOM))
+	}
+	return br
+}
@@ -0,0 +1,32 @@
+package webcsv
+
+import (
+	"testing"
+)
+
+func TestValidateCharset(t *testing.T) {
+	sch := qtySchema()
+
+	// the length is counted on the decoded characters
+	for cs, data := range map[string]string{
+		"latin1": "\xe9t\xe9,1\n",
+		"cp1252": "\x80\xe9\x9c,1\n",
+		"":       "\xef\xbb\xbf\xc3\xa9t\xc3\xa9,1\n",
+	} {
+		sch.Charset = cs
+		maps, err := sch.ValidateToMaps([]byte(data))
+		if err != nil {
+			t.Errorf("%s: %v", cs, err)
+			continue
+		}
+
+		if code := maps[0]["Code"]; code != "été" && code != "€éœ" {
+			t.Errorf("%s: got %q", cs, code)
+		}
+	}
+
+	sch.Charset = "ebcdic"
+	if _, err := sch.ValidateReturn([]byte("a,1\n")); err == nil {
+		t.Error("got no error, want the charset unknown")
+	}
+}
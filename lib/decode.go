@@ -0,0 +1,289 @@
+package webcsv
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// DecodeInto - validates the data against the schema and decodes the records into the slice of structs pointed by out.
+// When the schema has MapByHeader set, the values are matched to the columns by the header names, so the
+// input columns can be in any order, and fields of columns missing from the header take the default of the
+// column or keep their zero value.
+// Columns with an Index take their value from that position of the input, so the input could have columns
+// that are not decoded.
+// Struct fields are matched to schema columns by the webcsv tag (e.g. `webcsv:"LastName"`). Fields without
+// the tag are matched by their field name, and fields tagged with `webcsv:"-"` are skipped.
+func (sch *Schema) DecodeInto(data []byte, out interface{}) error {
+
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return errors.New(`DecodeInto requires a pointer to a slice of structs`)
+	}
+
+	if rv.Elem().Type().Elem().Kind() != reflect.Struct {
+		return errors.New(`DecodeInto requires a pointer to a slice of structs`)
+	}
+
+	recs, lines, pos, first, err := sch.validateData(data)
+	if err != nil {
+		return err
+	}
+
+	return sch.decodeRecords(recs[first:], lines[first:], pos, rv)
+}
+
+// DecodeRecords - decodes valid records in the order of the schema columns, such as the records of
+// ValidateReader, into the slice of structs pointed by out like DecodeInto. The records are not validated again.
+func (sch *Schema) DecodeRecords(recs [][]string, out interface{}) error {
+
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice ||
+		rv.Elem().Type().Elem().Kind() != reflect.Struct {
+		return errors.New(`DecodeRecords requires a pointer to a slice of structs`)
+	}
+
+	return sch.decodeRecords(recs, nil, nil, rv)
+}
+
+// decodeRecords - appends the records to the slice pointed by rv. Errors report the line of each record,
+// or its index from 1 without lines. Columns missing from the input, by the positions of validateMapped,
+// take their default or leave the field at its zero value.
+func (sch *Schema) decodeRecords(recs [][]string, lines []int, pos []int, rv reflect.Value) error {
+
+	slice := rv.Elem()
+	elemType := slice.Type().Elem()
+	fields := sch.fieldMap(elemType)
+
+	for i, rec := range recs {
+		item := reflect.New(elemType).Elem()
+		for cn, fi := range fields {
+			if fi == -1 || cn >= len(rec) || pos != nil && pos[cn] < 0 && sch.Columns[cn].Default == "" {
+				continue
+			}
+
+			if err := sch.decodeValue(item.Field(fi), &sch.Columns[cn], rec[cn]); err != nil {
+				line := i + 1
+				if lines != nil {
+					line = lines[i]
+				}
+				return fmt.Errorf("Column %d of line %d could not be decoded into field %s. Error: %s", cn, line, elemType.Field(fi).Name, err.Error())
+			}
+		}
+		slice = reflect.Append(slice, item)
+	}
+
+	rv.Elem().Set(slice)
+
+	return nil
+}
+
+// validateData - validates the data and returns the records with their physical lines, the position of each
+// schema column in the input (see validateMapped) and the index of the first data record
+func (sch *Schema) validateData(data []byte) (recs [][]string, lines []int, pos []int, first int, err error) {
+
+	var errs []ValidationError
+	recs, lines, pos, errs = sch.validateMapped(bytes.NewReader(data))
+	if err = joinErrors(errs); err != nil {
+		return nil, nil, nil, 0, err
+	}
+
+	// the header is not a data record
+	if sch.WithHeader && !sch.OmitHeader && len(recs) > 0 {
+		first = 1
+	}
+
+	return
+}
+
+// fieldMap - returns the struct field index for each schema column. A column with no matching field gets -1.
+func (sch *Schema) fieldMap(t reflect.Type) []int {
+
+	fields := make([]int, len(sch.Columns))
+	for cn := range fields {
+		fields[cn] = -1
+	}
+
+	for fi := 0; fi < t.NumField(); fi++ {
+		f := t.Field(fi)
+
+		// unexported fields can not be set
+		if f.PkgPath != "" {
+			continue
+		}
+
+		tag, ok := f.Tag.Lookup("webcsv")
+		if tag == "-" {
+			continue
+		}
+
+		// the tag could also hold the column type for SchemaFromStruct
+		name := tagName(tag)
+
+		if !ok || name == "" {
+			name = f.Name
+		}
+
+		for cn := range sch.Columns {
+			if fields[cn] == -1 && strings.EqualFold(sch.Columns[cn].Name, name) {
+				fields[cn] = fi
+				break
+			}
+		}
+	}
+
+	return fields
+}
+
+// decodeValue - sets a struct field from a validated value
+func (sch *Schema) decodeValue(fv reflect.Value, sc *SchemaColumn, cv string) error {
+
+	// empty values take the default of the column
+	if cv == "" {
+		cv = sc.Default
+	}
+
+	// empty values of nullable columns stay as zero values
+	if cv == "" && sc.Nullable {
+		return nil
+	}
+
+	// the unit is not part of the value
+	cv = strings.TrimSuffix(cv, sc.Unit)
+
+	if fv.Type() == timeType {
+		t, err := sc.parseTime(cv)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(cv)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(cv, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(cv, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(sch.normalizeDecimal(cv), fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(n)
+	case reflect.Bool:
+		b, err := sch.parseBool(cv)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+
+	return nil
+}
+
+// ValidateToMaps - validates the data against the schema and converts each record into a map keyed by the
+// column names. Values are converted to their Go types: int to int64, decimal to float64, bool to bool,
+// date, datetime and time to time.Time and the rest to string. Empty values take the default of the column.
+// Empty values of nullable columns and columns missing from the header without a default are nil.
+func (sch *Schema) ValidateToMaps(data []byte) ([]map[string]interface{}, error) {
+
+	recs, lines, pos, first, err := sch.validateData(data)
+	if err != nil {
+		return nil, err
+	}
+
+	maps := make([]map[string]interface{}, 0, len(recs)-first)
+	for i, rec := range recs {
+		if i < first {
+			continue
+		}
+
+		m, err := sch.recordToMap(rec, pos)
+		if err != nil {
+			return nil, fmt.Errorf("Line %d: %s", lines[i], err.Error())
+		}
+		maps = append(maps, m)
+	}
+
+	return maps, nil
+}
+
+// RecordToMap - converts a valid record in the order of the schema columns into a map keyed by the column
+// names, with the values in their Go types as ValidateToMaps does. The record is not validated again.
+func (sch *Schema) RecordToMap(rec []string) (map[string]interface{}, error) {
+	return sch.recordToMap(rec, nil)
+}
+
+// recordToMap - converts the record into a map like RecordToMap. Columns missing from the input, by the
+// positions of validateMapped, take their default or are nil.
+func (sch *Schema) recordToMap(rec []string, pos []int) (map[string]interface{}, error) {
+	m := make(map[string]interface{}, len(sch.Columns))
+	for cn := range sch.Columns {
+		sc := &sch.Columns[cn]
+
+		if pos != nil && pos[cn] < 0 && sc.Default == "" {
+			m[sc.Name] = nil
+			continue
+		}
+
+		cv := ""
+		if cn < len(rec) {
+			cv = rec[cn]
+		}
+
+		v, err := sch.typedValue(sc, cv)
+		if err != nil {
+			return nil, fmt.Errorf("Column %d could not be converted to %s. Error: %s", cn, sc.Type, err.Error())
+		}
+		m[sc.Name] = v
+	}
+
+	return m, nil
+}
+
+// typedValue - converts a validated value to the Go type of its column
+func (sch *Schema) typedValue(sc *SchemaColumn, cv string) (interface{}, error) {
+
+	// empty values take the default of the column
+	if cv == "" {
+		cv = sc.Default
+	}
+
+	if cv == "" && sc.Nullable {
+		return nil, nil
+	}
+
+	cv = strings.TrimSuffix(cv, sc.Unit)
+
+	switch sc.Type {
+	case "int":
+		return strconv.ParseInt(cv, 10, 64)
+	case "decimal":
+		return strconv.ParseFloat(sch.normalizeDecimal(cv), 64)
+	case "bool":
+		return sch.parseBool(cv)
+	case "date", "datetime", "time":
+		return sc.parseTime(cv)
+	}
+
+	return cv, nil
+}
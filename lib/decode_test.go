@@ -0,0 +1,170 @@
+package webcsv
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeMissingColumn(t *testing.T) {
+	sch := qtySchema()
+	sch.WithHeader = true
+	sch.MapByHeader = true
+
+	type item struct {
+		Code string
+		Qty  int
+	}
+
+	var items []item
+	if err := sch.DecodeInto([]byte("Code\nab\n"), &items); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(items) != 1 || items[0] != (item{Code: "ab"}) {
+		t.Fatalf("got %v, want the code with a zero quantity", items)
+	}
+
+	maps, err := sch.ValidateToMaps([]byte("Qty,Code\n5,ab\n"))
+	if err != nil || maps[0]["Code"] != "ab" || maps[0]["Qty"] != int64(5) {
+		t.Fatalf("got %v %v, want the values mapped by the header", maps, err)
+	}
+
+	maps, err = sch.ValidateToMaps([]byte("Code\nab\n"))
+	if v, ok := maps[0]["Qty"]; err != nil || !ok || v != nil {
+		t.Fatalf("got %v %v, want a nil quantity", maps, err)
+	}
+}
+
+func TestDecodeDefaults(t *testing.T) {
+	sch, err := ParseSchema("ver:1.0; S:string(5)=abc,I:int=7,F:decimal(5,2)=1.25,B:bool=true," +
+		"D:date=2021-03-04,DT:datetime=2021-03-04T05:06:07Z,T:time=08:09,U:uuid=123e4567-e89b-12d3-a456-426614174000," +
+		"E:email=a@b.co,N:enum(x;y)=y")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type item struct {
+		S  string
+		I  int
+		F  float64
+		B  bool
+		D  time.Time
+		DT time.Time
+		T  time.Time
+		U  string
+		E  string
+		N  string
+	}
+
+	var items []item
+	if err = sch.DecodeInto([]byte(",,,,,,,,,\nxyz,1,,,,,,,,\n"), &items); err != nil {
+		t.Fatal(err)
+	}
+
+	want := item{
+		S:  "abc",
+		I:  7,
+		F:  1.25,
+		B:  true,
+		D:  time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC),
+		DT: time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC),
+		T:  time.Date(0, 1, 1, 8, 9, 0, 0, time.UTC),
+		U:  "123e4567-e89b-12d3-a456-426614174000",
+		E:  "a@b.co",
+		N:  "y",
+	}
+	if len(items) != 2 || items[0] != want {
+		t.Fatalf("got %+v, want %+v", items, want)
+	}
+
+	// a column missing from the header also takes its default
+	sch = qtySchema()
+	sch.WithHeader, sch.MapByHeader = true, true
+	sch.Columns[1].Default = "3"
+
+	maps, err := sch.ValidateToMaps([]byte("Code\nab\n"))
+	if err != nil || maps[0]["Qty"] != int64(3) {
+		t.Fatalf("got %v %v, want the default quantity", maps, err)
+	}
+}
+
+func TestParseSchemaInvalidDefault(t *testing.T) {
+	for _, raw := range []string{
+		"ver:1.0; A:string(3),B:int=abc",
+		"ver:1.0; A:string(3),C:bool=maybe",
+		"ver:1.0; A:string(3)=abcd",
+		"ver:1.0; A:int[0..10]=11",
+		"ver:1.0; A:enum(x;y)=z",
+	} {
+		if _, err := ParseSchema(raw); err == nil {
+			t.Errorf("%q: got no error, want the default rejected", raw)
+		}
+	}
+
+	sch := qtySchema()
+	sch.Columns[1].Default = "abc"
+	if err := sch.SelfCheck(); err == nil {
+		t.Error("got no error from SelfCheck, want the default rejected")
+	}
+
+	// the default is checked with the separators of the schema
+	sch.Columns[1].Type, sch.Columns[1].Precision, sch.Columns[1].Scale = "decimal", 5, 2
+	sch.Columns[1].Default = "1,5"
+	sch.DecimalSeparator = ","
+	sch.Delimiter = ";"
+	if err := sch.SelfCheck(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDecodeErrorLine(t *testing.T) {
+	sch := qtySchema()
+	sch.Columns[0].Length = 10
+
+	// the quoted value of the first record spans two lines, so the second record starts on line 3
+	type item struct {
+		Code string
+		Qty  int8
+	}
+
+	var items []item
+	err := sch.DecodeInto([]byte("\"a\nb\",1\nc,300\n"), &items)
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Fatalf("got %v, want the error on line 3", err)
+	}
+
+	sch.Columns[1] = SchemaColumn{Name: "Qty", Type: "decimal", Precision: 400}
+	sch.ExactDecimal = true
+	_, err = sch.ValidateToMaps([]byte("\"a\nb\",1\nc," + strings.Repeat("9", 310) + "\n"))
+	if err == nil || !strings.HasPrefix(err.Error(), "Line 3:") {
+		t.Fatalf("got %v, want the error on line 3", err)
+	}
+}
+
+func TestDecodeRecords(t *testing.T) {
+	sch := qtySchema()
+
+	type item struct {
+		Code string
+		Qty  int8
+	}
+
+	recs, err := sch.ValidateReturn([]byte("ab,1\ncd,2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items []item
+	if err = sch.DecodeRecords(recs, &items); err != nil || len(items) != 2 || items[1] != (item{"cd", 2}) {
+		t.Fatalf("got %v %v, want both records", items, err)
+	}
+
+	if err = sch.DecodeRecords([][]string{{"ab", "300"}}, &items); err == nil {
+		t.Fatal("got no error, want the quantity out of the field range")
+	}
+
+	if err = sch.DecodeRecords(recs, items); err == nil {
+		t.Fatal("got no error, want a pointer required")
+	}
+}
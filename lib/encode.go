@@ -0,0 +1,131 @@
+package webcsv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EncodeFrom - serializes a slice of structs to CSV in the order of the schema columns.
+// Struct fields are matched to schema columns the same way DecodeInto does. A header of the column names
+// is written first if the schema has WithHeader set, and the values are separated by the schema delimiter,
+// so the output can be validated again by the same schema.
+func (sch *Schema) EncodeFrom(in interface{}) ([]byte, error) {
+
+	rv := reflect.ValueOf(in)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() != reflect.Struct {
+		return nil, errors.New(`EncodeFrom requires a slice of structs`)
+	}
+
+	if _, err := sch.comma(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	rw := sch.NewRecordWriter(&buf)
+	for i := 0; i < rv.Len(); i++ {
+		if err := rw.WriteStruct(rv.Index(i).Interface()); err != nil {
+			return nil, fmt.Errorf("Item %d: %s", i, err.Error())
+		}
+	}
+
+	if err := rw.Flush(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// WriteRecords - writes the records to w as CSV separated by the schema delimiter. A header of the column
+// names is written first if the schema has WithHeader set. The records should not include a header.
+func (sch *Schema) WriteRecords(w io.Writer, records [][]string) error {
+
+	comma, err := sch.comma()
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+
+	if sch.WithHeader {
+		if err = cw.Write(sch.header()); err != nil {
+			return err
+		}
+	}
+
+	if err = cw.WriteAll(records); err != nil {
+		return err
+	}
+
+	return cw.Error()
+}
+
+// header - returns the column names as a header record
+func (sch *Schema) header() []string {
+	hdr := make([]string, len(sch.Columns))
+	for cn, c := range sch.Columns {
+		hdr[cn] = c.Name
+	}
+	return hdr
+}
+
+// encodeValue - formats a struct field by its schema column
+func (sch *Schema) encodeValue(fv reflect.Value, sc *SchemaColumn) (string, error) {
+
+	if fv.Type() == timeType {
+		t := fv.Interface().(time.Time)
+
+		// RFC 3339 values are parsed with their fraction of a second, so it is kept
+		if sc.Type == "datetime" && sc.Format == "" {
+			return t.Format(time.RFC3339Nano), nil
+		}
+		return t.Format(sc.layout()), nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		return fv.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(fv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(fv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		prec := -1
+		if sc.Type == "decimal" {
+			prec = sc.Scale
+		}
+		v := strconv.FormatFloat(fv.Float(), 'f', prec, fv.Type().Bits())
+		if sch.DecimalSeparator != "" {
+			v = strings.Replace(v, ".", sch.DecimalSeparator, 1)
+		}
+		return v, nil
+	case reflect.Bool:
+		return sch.formatBool(fv.Bool()), nil
+	}
+
+	return "", fmt.Errorf("unsupported field type %s", fv.Type())
+}
+
+// formatBool - returns the first token of TrueValues or FalseValues for the value, so the schema accepts it
+// again. Without tokens, the value is formatted by strconv.FormatBool.
+func (sch *Schema) formatBool(v bool) string {
+	if v && len(sch.TrueValues) > 0 {
+		return sch.TrueValues[0]
+	}
+	if !v && len(sch.FalseValues) > 0 {
+		return sch.FalseValues[0]
+	}
+	return strconv.FormatBool(v)
+}
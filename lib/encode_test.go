@@ -0,0 +1,67 @@
+package webcsv
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncodeBoolTokens(t *testing.T) {
+	sch := qtySchema()
+	sch.Columns[1] = SchemaColumn{Name: "Active", Type: "bool"}
+	sch.TrueValues = []string{"Y", "yes"}
+	sch.FalseValues = []string{"N", "no"}
+
+	type item struct {
+		Code   string
+		Active bool
+	}
+
+	in := []item{{"ab", true}, {"cd", false}}
+	data, err := sch.EncodeFrom(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "ab,Y\ncd,N\n" {
+		t.Fatalf("got %q, want the first bool tokens", data)
+	}
+
+	var out []item
+	if err = sch.DecodeInto(data, &out); err != nil || len(out) != 2 || out[0] != in[0] || out[1] != in[1] {
+		t.Fatalf("got %v %v, want %v", out, err, in)
+	}
+
+	var buf bytes.Buffer
+	rw := sch.NewRecordWriter(&buf)
+	if err = rw.WriteRecord("ef", false); err != nil {
+		t.Fatal(err)
+	}
+	if err = rw.Flush(); err != nil || buf.String() != "ef,N\n" {
+		t.Fatalf("got %q %v, want the false token", buf.String(), err)
+	}
+
+	// without tokens, the values are written as strconv formats them
+	sch.TrueValues, sch.FalseValues = nil, nil
+	if data, err = sch.EncodeFrom(in); err != nil || string(data) != "ab,true\ncd,false\n" {
+		t.Fatalf("got %q %v, want true and false", data, err)
+	}
+}
+
+func TestWriteRecordCount(t *testing.T) {
+	sch := qtySchema()
+
+	var buf bytes.Buffer
+	rw := sch.NewRecordWriter(&buf)
+	for _, values := range [][]interface{}{{"ab"}, {"ab", 1, "x"}, {}} {
+		if err := rw.WriteRecord(values...); err == nil {
+			t.Errorf("%v: got no error, want the values rejected", values)
+		}
+	}
+
+	if err := rw.WriteRecord("ab", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := rw.Flush(); err != nil || buf.String() != "ab,\n" {
+		t.Fatalf("got %q %v, want only the record of both values", buf.String(), err)
+	}
+}
@@ -0,0 +1,126 @@
+package webcsv
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+)
+
+// escapeReader - converts data with backslash escapes (Doe\, Jr.) to standard CSV that encoding/csv reads.
+// A backslash makes the next character part of the value, be it the delimiter, a line break or another
+// backslash. Double quotes are ordinary characters. The values that need it are quoted in the output and
+// the line breaks are kept, so the records start at the same lines as in the data.
+type escapeReader struct {
+	rd      *bufio.Reader
+	comma   rune
+	comment rune
+	out     bytes.Buffer // converted records not yet returned
+	err     error        // error of the last read from rd
+}
+
+// newEscapeReader - creates a reader that converts the backslash escapes of the data from rd
+func newEscapeReader(rd io.Reader, comma rune, comment rune) *escapeReader {
+	return &escapeReader{
+		rd:      bufio.NewReader(rd),
+		comma:   comma,
+		comment: comment,
+	}
+}
+
+// Read - implements io.Reader
+func (er *escapeReader) Read(p []byte) (int, error) {
+	for er.out.Len() == 0 && er.err == nil {
+		er.err = er.next()
+	}
+
+	if er.out.Len() > 0 {
+		return er.out.Read(p)
+	}
+
+	return 0, er.err
+}
+
+// next - converts the next record of the data
+func (er *escapeReader) next() error {
+
+	// comment lines are copied as they are, since csv.Reader skips them
+	if er.comment != 0 {
+		if r, _, err := er.rd.ReadRune(); err == nil {
+			er.rd.UnreadRune()
+			if r == er.comment {
+				line, err := er.rd.ReadString('\n')
+				er.out.WriteString(line)
+				return err
+			}
+		}
+	}
+
+	var field strings.Builder
+	quote := false
+	first := true
+	n := 0
+	for ; ; n++ {
+		r, _, err := er.rd.ReadRune()
+		if err != nil {
+			// the last record could have no line break
+			if n > 0 {
+				er.writeField(field.String(), quote, first)
+			}
+			return err
+		}
+
+		switch r {
+		case '\\':
+			e, _, err := er.rd.ReadRune()
+			if err != nil {
+				// a backslash at the end of the data has nothing to escape
+				field.WriteRune(r)
+				continue
+			}
+
+			field.WriteRune(e)
+			if e == er.comma || e == '"' || e == '\r' || e == '\n' {
+				quote = true
+			}
+		case er.comma:
+			er.writeField(field.String(), quote, first)
+			er.out.WriteRune(er.comma)
+			field.Reset()
+			quote, first = false, false
+		case '\r':
+			// a carriage return is part of the line break before a line feed
+			if b, err := er.rd.Peek(1); err == nil && b[0] == '\n' {
+				continue
+			}
+			field.WriteRune(r)
+			quote = true
+		case '\n':
+			er.writeField(field.String(), quote, first)
+			er.out.WriteByte('\n')
+			return nil
+		default:
+			field.WriteRune(r)
+			if r == '"' {
+				quote = true
+			}
+		}
+	}
+}
+
+// writeField - writes the value to the output, in quotes if it has characters that are special to CSV.
+// The first value of a record is also quoted if it starts with the comment character.
+func (er *escapeReader) writeField(v string, quote bool, first bool) {
+	if first && er.comment != 0 && strings.HasPrefix(v, string(er.comment)) {
+		quote = true
+	}
+
+	if !quote {
+		er.out.WriteString(v)
+		return
+	}
+
+	er.out.WriteByte('"')
+	er.out.WriteString(strings.ReplaceAll(v, `"`, `""`))
+	er.out.WriteByte('"')
+}
@@ -0,0 +1,175 @@
+package webcsv
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Modes of the data
+const (
+	ModeDelimited = "delimited" // values are separated by the delimiter (the default)
+	ModeFixed     = "fixed"     // values are cut from each line by the Start and width of the columns
+)
+
+// recordSource - reads the records of the data with the position of their values, as csv.Reader does
+type recordSource interface {
+	Read() ([]string, error)
+	FieldPos(field int) (line, column int)
+}
+
+// newSource - creates the reader of the records for the mode of the schema
+func (sch *Schema) newSource(rd io.Reader) (recordSource, error) {
+	switch sch.Mode {
+	case "", ModeDelimited:
+		return sch.newReader(rd)
+	case ModeFixed:
+		return sch.newFixedReader(rd)
+	}
+
+	return nil, fmt.Errorf("Unknown mode %q", sch.Mode)
+}
+
+// fixedReader - cuts the lines of fixed-width data into the values of the columns. The spaces that pad the
+// values are removed. Empty lines and comment lines are skipped.
+type fixedReader struct {
+	rd      *bufio.Reader
+	comment rune
+	starts  []int // start of each column in characters
+	ends    []int // end of each column in characters, or -1 for the rest of the line
+	line    int   // line of the last record
+	err     error // error of the last read from rd
+}
+
+// newFixedReader - creates the reader of fixed-width data. A column with a Start of zero, other than the
+// first, starts where the previous column ends. Only the last column could have no width, and it takes
+// the rest of the line.
+func (sch *Schema) newFixedReader(rd io.Reader) (*fixedReader, error) {
+
+	rd, err := sch.input(rd)
+	if err != nil {
+		return nil, err
+	}
+
+	fr := &fixedReader{
+		rd:      bufio.NewReader(rd),
+		comment: sch.CommentChar,
+		starts:  make([]int, len(sch.Columns)),
+		ends:    make([]int, len(sch.Columns)),
+	}
+
+	pos := 0
+	for i, c := range sch.Columns {
+		if c.Start < 0 {
+			return nil, fmt.Errorf("Column %s has a negative start of %d", c.Name, c.Start)
+		}
+
+		if c.Start > 0 {
+			pos = c.Start
+		}
+		fr.starts[i] = pos
+
+		w := c.fixedWidth()
+		if w == 0 {
+			if i < len(sch.Columns)-1 {
+				return nil, fmt.Errorf("Column %s needs a length in fixed mode", c.Name)
+			}
+			fr.ends[i] = -1
+			continue
+		}
+
+		pos += w
+		fr.ends[i] = pos
+	}
+
+	return fr, nil
+}
+
+// fixedWidth - returns the width of the column in fixed mode. Columns without a length are as wide as the
+// widest value of their type: the digits of the precision with the sign and the decimal point for decimals,
+// the digits of the bits with the sign for ints, false for bools and the layout for dates and times. The
+// unit of ints and decimals is included. Other columns without a length have no width.
+func (sc *SchemaColumn) fixedWidth() int {
+	if sc.Length > 0 {
+		return sc.Length
+	}
+
+	unit := utf8.RuneCountInString(sc.Unit)
+
+	switch sc.Type {
+	case "decimal":
+		if sc.Precision == 0 {
+			return 0
+		}
+		if sc.Scale > 0 {
+			return sc.Precision + 2 + unit
+		}
+		return sc.Precision + 1 + unit
+	case "int":
+		switch sc.Bits {
+		case 8:
+			return len("-128") + unit
+		case 16:
+			return len("-32768") + unit
+		case 32:
+			return len("-2147483648") + unit
+		}
+		return len("-9223372036854775808") + unit
+	case "bool":
+		return len("false")
+	case "date", "datetime", "time":
+		return len(sc.layout())
+	}
+
+	return 0
+}
+
+// Read - reads the next line and cuts it into the values of the columns. Columns that start after the end
+// of the line are missing from the record.
+func (fr *fixedReader) Read() ([]string, error) {
+	for {
+		if fr.err != nil {
+			return nil, fr.err
+		}
+
+		var s string
+		s, fr.err = fr.rd.ReadString('\n')
+		if fr.err != nil && s == "" {
+			return nil, fr.err
+		}
+		fr.line++
+
+		s = strings.TrimSuffix(strings.TrimSuffix(s, "\n"), "\r")
+		if s == "" || fr.comment != 0 && strings.HasPrefix(s, string(fr.comment)) {
+			continue
+		}
+
+		// positions are in characters, not bytes
+		line := []rune(s)
+		rec := make([]string, 0, len(fr.starts))
+		for i, start := range fr.starts {
+			if start >= len(line) {
+				break
+			}
+
+			end := fr.ends[i]
+			if end == -1 || end > len(line) {
+				end = len(line)
+			}
+
+			rec = append(rec, strings.TrimSpace(string(line[start:end])))
+		}
+
+		return rec, nil
+	}
+}
+
+// FieldPos - returns the line of the last record and the column where the value starts, starting from 1
+func (fr *fixedReader) FieldPos(field int) (line, column int) {
+	if field < 0 || field >= len(fr.starts) {
+		return fr.line, 0
+	}
+	return fr.line, fr.starts[field] + 1
+}
@@ -0,0 +1,37 @@
+package webcsv
+
+import (
+	"testing"
+)
+
+func TestFixedModeParsed(t *testing.T) {
+	raw := "ver:1.0,hdr:false,del:,,mode:fixed; A:decimal(5,2),B:bool,C:string(3)@20"
+	sch, err := ParseSchema(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sch.Mode != ModeFixed || sch.Columns[2].Start != 20 {
+		t.Fatalf("got mode %q and start %d", sch.Mode, sch.Columns[2].Start)
+	}
+
+	if printed := sch.PrintSchema(); printed != raw {
+		t.Fatalf("got %q, want %q", printed, raw)
+	}
+
+	// A is 7 wide (-123.45) and B is 5 wide (false), so C starts after padding
+	maps, err := sch.ValidateToMaps([]byte("123.45 true         abc\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if maps[0]["A"] != 123.45 || maps[0]["B"] != true || maps[0]["C"] != "abc" {
+		t.Fatalf("got %v", maps[0])
+	}
+
+	delimited := sch.Clone()
+	delimited.Mode = ""
+	if diff := sch.Diff(delimited); len(diff) != 1 {
+		t.Fatalf("got %v, want the mode mismatch", diff)
+	}
+}
@@ -0,0 +1,117 @@
+package webcsv
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// SchemaFromStruct - creates a schema with a column for each exported field of the struct, or of the struct
+// pointed by v, in the order of the fields. The column type comes from the field type: string to string,
+// integers to int, except uint and uint64 to decimal(20,0) since they could be above the int range, floats
+// to decimal, bool to bool and time.Time to datetime.
+//
+// The webcsv tag names the column as in DecodeInto, and the rest of the tag after a comma is added to the
+// type, so it holds its parameters and suffixes in the schema syntax:
+//
+//	LastName string    `webcsv:"LastName,(50)"`    // LastName:string(50)
+//	Height   float64   `webcsv:",(13,3)?"`         // Height:decimal(13,3)?
+//	DateBorn time.Time `webcsv:",date"`            // DateBorn:date
+//
+// A rest that starts with a letter is the whole type. String fields without a length are string(4000),
+// and float fields must have the precision of their decimal. Fields tagged with `webcsv:"-"` are skipped.
+// The schema has no version and header, and its delimiter is a comma.
+//
+// The structs encoded by EncodeFrom with the schema are decoded back by DecodeInto to the same values, with
+// these exceptions:
+//   - times keep their instant, but their location could become a fixed zone and their monotonic clock
+//     reading is removed, so they should be compared with time.Time.Equal. Date and time columns also
+//     remove the parts of the time that are not in their layout
+//   - floats are rounded to the scale of their decimal, and must fit in its precision
+//   - strings must fit in the length of their column, and line breaks of \r\n in them become \n
+//   - a record of only whitespace at the end of the data is skipped unless StrictTrailingRow is set
+func SchemaFromStruct(v interface{}) (*Schema, error) {
+
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, errors.New(`SchemaFromStruct requires a struct or a pointer to a struct`)
+	}
+
+	cols := make([]string, 0, t.NumField())
+	for fi := 0; fi < t.NumField(); fi++ {
+		f := t.Field(fi)
+
+		// unexported fields can not be decoded
+		if f.PkgPath != "" {
+			continue
+		}
+
+		tag := f.Tag.Get("webcsv")
+		if tag == "-" {
+			continue
+		}
+
+		name, def := tagName(tag), ""
+		if pos := strings.Index(tag, ","); pos != -1 {
+			def = strings.TrimSpace(tag[pos+1:])
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		typ := ""
+		switch k := f.Type.Kind(); {
+		case f.Type == timeType:
+			typ = "datetime"
+		case k == reflect.String:
+			typ = "string"
+		case k == reflect.Uint || k == reflect.Uint64:
+			// the values above the int range would not pass as int
+			typ = "decimal(20,0)"
+		case k >= reflect.Int && k <= reflect.Uint64:
+			typ = "int"
+		case k == reflect.Float32 || k == reflect.Float64:
+			typ = "decimal"
+		case k == reflect.Bool:
+			typ = "bool"
+		}
+
+		switch {
+		case def != "" && unicode.IsLetter(rune(def[0])):
+			typ = def
+		case typ == "":
+			return nil, fmt.Errorf("Field %s of type %s has no column type", f.Name, f.Type)
+		case typ == "string" && !strings.HasPrefix(def, "("):
+			typ = "string(4000)" + def
+		case typ == "decimal" && !strings.HasPrefix(def, "("):
+			return nil, fmt.Errorf("Field %s needs the precision of its decimal in the webcsv tag", f.Name)
+		default:
+			typ += def
+		}
+
+		cols = append(cols, name+":"+typ)
+	}
+
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("Struct %s has no fields for the columns", t)
+	}
+
+	sch, err := ParseSchema("ver:,hdr:false,del:,; " + strings.Join(cols, ","))
+	if err != nil {
+		return nil, err
+	}
+
+	return sch, nil
+}
+
+// tagName - returns the column name of a webcsv tag, which is before the first comma
+func tagName(tag string) string {
+	return strings.TrimSpace(strings.SplitN(tag, ",", 2)[0])
+}
@@ -0,0 +1,35 @@
+package webcsv
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSchemaFromStructUnsigned(t *testing.T) {
+	type counter struct {
+		Name  string `webcsv:",(10)"`
+		Small uint8
+		Big   uint64
+		Size  uint
+	}
+
+	sch, err := SchemaFromStruct(counter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := []counter{{Name: "max", Small: math.MaxUint8, Big: math.MaxUint64, Size: math.MaxUint32 + 1}}
+	data, err := sch.EncodeFrom(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out []counter
+	if err = sch.DecodeInto(data, &out); err != nil {
+		t.Fatalf("%s: %v", data, err)
+	}
+
+	if len(out) != 1 || out[0] != in[0] {
+		t.Fatalf("got %v, want %v", out, in)
+	}
+}
@@ -0,0 +1,341 @@
+package webcsvhttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	webcsv "webcsv/lib"
+
+	"github.com/gorilla/mux"
+)
+
+// Store - storage of the records of a resource. Records are in the order of the schema columns and are
+// matched by the values of the primary key columns of the schema, or by all the values if the schema
+// has no primary key.
+type Store interface {
+	Insert(records ...[]string) error
+	Update(record []string) (bool, error) // false if no stored record has the same key
+	Delete(key []string) (bool, error)    // key values in the order of the primary key columns
+	List() ([][]string, error)
+}
+
+// MemoryStore - store that keeps the records in memory. It is safe for concurrent use.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	records [][]string
+	keys    []int // index of the key columns
+}
+
+// NewMemoryStore - creates an empty store that matches records by the primary key of the schema. It returns
+// an error if a primary key column is not in the schema.
+func NewMemoryStore(sch *webcsv.Schema) (*MemoryStore, error) {
+	keys, err := keyColumns(sch)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MemoryStore{
+		records: make([][]string, 0),
+		keys:    keys,
+	}, nil
+}
+
+// key - returns the key values of a record joined with a NUL character
+func (ms *MemoryStore) key(rec []string) string {
+	if ms.keys == nil {
+		return strings.Join(rec, "\x00")
+	}
+
+	vals := make([]string, len(ms.keys))
+	for i, cn := range ms.keys {
+		if cn < len(rec) {
+			vals[i] = rec[cn]
+		}
+	}
+	return strings.Join(vals, "\x00")
+}
+
+// Insert - adds the records to the store
+func (ms *MemoryStore) Insert(records ...[]string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for _, rec := range records {
+		ms.records = append(ms.records, append([]string(nil), rec...))
+	}
+	return nil
+}
+
+// Update - replaces the stored record with the same key
+func (ms *MemoryStore) Update(record []string) (bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	key := ms.key(record)
+	for i := range ms.records {
+		if ms.key(ms.records[i]) == key {
+			ms.records[i] = append([]string(nil), record...)
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Delete - removes the stored records with the key values
+func (ms *MemoryStore) Delete(key []string) (bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	k := strings.Join(key, "\x00")
+	found := false
+	kept := ms.records[:0]
+	for _, rec := range ms.records {
+		if ms.key(rec) == k {
+			found = true
+			continue
+		}
+		kept = append(kept, rec)
+	}
+	ms.records = kept
+
+	return found, nil
+}
+
+// List - returns a copy of the stored records
+func (ms *MemoryStore) List() ([][]string, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	return append([][]string(nil), ms.records...), nil
+}
+
+// keyColumns - returns the index of the primary key columns of the schema, or nil if it has none
+func keyColumns(sch *webcsv.Schema) ([]int, error) {
+	if len(sch.PrimaryKey) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]int, len(sch.PrimaryKey))
+	for i, name := range sch.PrimaryKey {
+		keys[i] = -1
+		for cn, c := range sch.Columns {
+			if c.Name == name {
+				keys[i] = cn
+				break
+			}
+		}
+
+		if keys[i] == -1 {
+			return nil, fmt.Errorf("primary key column %q is not in the schema", name)
+		}
+	}
+	return keys, nil
+}
+
+// filterRecords - returns the records whose values match every query parameter named after a column of the
+// schema. Column names are matched in any case, so lastName filters LastName.
+func filterRecords(sch *webcsv.Schema, recs [][]string, q url.Values) [][]string {
+	filters := make(map[int]string)
+	for k := range q {
+		for cn, c := range sch.Columns {
+			if strings.EqualFold(c.Name, k) {
+				filters[cn] = q.Get(k)
+			}
+		}
+	}
+
+	if len(filters) == 0 {
+		return recs
+	}
+
+	sel := make([][]string, 0)
+	for _, rec := range recs {
+		match := true
+		for cn, v := range filters {
+			if cn >= len(rec) || rec[cn] != v {
+				match = false
+				break
+			}
+		}
+
+		if match {
+			sel = append(sel, rec)
+		}
+	}
+
+	return sel
+}
+
+// queryCount - returns the non-negative number of the query parameter, or def if it is not set
+func queryCount(q url.Values, name string, def int) (int, error) {
+	v := q.Get(name)
+	if v == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("Invalid %s", name)
+	}
+	return n, nil
+}
+
+// RegisterCRUD - attaches the handlers of a resource at the prefix of the router:
+//
+//	POST   inserts the records of the body, or updates the ones with the same key with ?mode=upsert
+//	PUT    updates the stored records with the same key as the records of the body
+//	GET    returns the stored records as CSV with the schema in the Content-Schema header, or as JSON
+//	       objects if the client accepts application/json. Query parameters named after the columns filter
+//	       the records, limit and offset page them, and hdr=true adds the column names as the first row.
+//	HEAD   returns the schema headers of GET without the records
+//	DELETE deletes the stored record whose key is in the query parameters named after the key columns
+//
+// The bodies of POST and PUT are validated against the schema by ValidateMiddleware.
+func RegisterCRUD(r *mux.Router, prefix string, sch *webcsv.Schema, store Store) {
+
+	write := ValidateMiddleware(sch, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		recs, _ := Records(req)
+
+		if req.Method == "POST" && req.URL.Query().Get("mode") != "upsert" {
+			if err := store.Insert(recs...); err != nil {
+				WriteError(w, http.StatusInternalServerError, fmt.Sprintf("Store: %v", err))
+				return
+			}
+
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(fmt.Sprintf("OK,Insert,inserted:%d", len(recs))))
+			return
+		}
+
+		inserted, updated, unmatched := 0, 0, 0
+		for _, rec := range recs {
+			ok, err := store.Update(rec)
+			if err == nil && !ok && req.Method == "POST" {
+				err = store.Insert(rec)
+				inserted++
+			}
+
+			if err != nil {
+				WriteError(w, http.StatusInternalServerError, fmt.Sprintf("Store: %v", err))
+				return
+			}
+
+			if ok {
+				updated++
+			} else if req.Method == "PUT" {
+				unmatched++
+			}
+		}
+
+		if req.Method == "POST" {
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(fmt.Sprintf("OK,Upsert,inserted:%d,updated:%d", inserted, updated)))
+			return
+		}
+
+		w.Write([]byte(fmt.Sprintf("OK,Update,updated:%d,unmatched:%d", updated, unmatched)))
+	}))
+
+	r.Handle(prefix, write).Methods("POST", "PUT")
+
+	r.HandleFunc(prefix, func(w http.ResponseWriter, req *http.Request) {
+
+		// The client could request the column names as the first row, so the output describes itself
+		q := req.URL.Query()
+		osch := sch
+		if hdr, _ := strconv.ParseBool(q.Get("hdr")); hdr {
+			osch = sch.Clone()
+			osch.WithHeader = true
+		}
+		w.Header().Set("Content-Schema", osch.PrintSchema())
+		w.Header().Set("Content-Schema-Hash", osch.Fingerprint())
+
+		// HEAD only returns the schema headers, so that clients can discover the schema cheaply
+		if req.Method == "HEAD" {
+			return
+		}
+
+		limit, err := queryCount(q, "limit", -1)
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		offset, err := queryCount(q, "offset", 0)
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		recs, err := store.List()
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, fmt.Sprintf("Store: %v", err))
+			return
+		}
+
+		sel := filterRecords(sch, recs, q)
+		w.Header().Set("X-Total-Count", strconv.Itoa(len(sel)))
+
+		if offset > len(sel) {
+			offset = len(sel)
+		}
+		sel = sel[offset:]
+		if limit != -1 && limit < len(sel) {
+			sel = sel[:limit]
+		}
+
+		// JSON objects are keyed by the column names, with numbers and booleans in their types.
+		// The stored records are already valid, so they are converted without validating them again.
+		if strings.Contains(req.Header.Get("Accept"), "application/json") {
+			maps := make([]map[string]interface{}, 0, len(sel))
+			for _, rec := range sel {
+				m, err := sch.RecordToMap(rec)
+				if err != nil {
+					WriteError(w, http.StatusInternalServerError, fmt.Sprintf("Encode: %v", err))
+					return
+				}
+				maps = append(maps, m)
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(maps)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/csv")
+		if err = osch.WriteRecords(w, sel); err != nil {
+			WriteError(w, http.StatusInternalServerError, fmt.Sprintf("Encode: %v", err))
+		}
+	}).Methods("GET", "HEAD")
+
+	r.HandleFunc(prefix, func(w http.ResponseWriter, req *http.Request) {
+		if len(sch.PrimaryKey) == 0 {
+			WriteError(w, http.StatusBadRequest, "Schema has no primary key")
+			return
+		}
+
+		q := req.URL.Query()
+		key := make([]string, len(sch.PrimaryKey))
+		for i, name := range sch.PrimaryKey {
+			key[i] = q.Get(name)
+		}
+
+		ok, err := store.Delete(key)
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, fmt.Sprintf("Store: %v", err))
+			return
+		}
+
+		if !ok {
+			WriteError(w, http.StatusNotFound, "Not found")
+			return
+		}
+
+		w.Write([]byte("OK,Delete"))
+	}).Methods("DELETE")
+}
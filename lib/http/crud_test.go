@@ -0,0 +1,98 @@
+package webcsvhttp
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// send - sends the request to the server with the schema fingerprint and returns the status and the body
+func send(t *testing.T, srv *httptest.Server, method, target, body, hash string) (int, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, srv.URL+target, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Schema-Hash", hash)
+
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(b)
+}
+
+func TestRegisterCRUD(t *testing.T) {
+	sch := testSchema()
+	store, err := NewMemoryStore(sch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := mux.NewRouter()
+	RegisterCRUD(r, "/items", sch, store)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	hash := sch.Fingerprint()
+	for _, step := range []struct {
+		method, target, body string
+		status               int
+		want                 string
+	}{
+		{"POST", "/items", "a,1\nb,2\n", http.StatusCreated, "OK,Insert,inserted:2"},
+		{"POST", "/items", "a,1\na,2\n", http.StatusBadRequest, ""},
+		{"GET", "/items", "", http.StatusOK, "a,1\nb,2\n"},
+		{"PUT", "/items", "a,5\nc,3\n", http.StatusOK, "OK,Update,updated:1,unmatched:1"},
+		{"POST", "/items?mode=upsert", "b,7\nc,3\n", http.StatusCreated, "OK,Upsert,inserted:1,updated:1"},
+		{"GET", "/items?limit=2&offset=1", "", http.StatusOK, "b,7\nc,3\n"},
+		{"GET", "/items?code=c", "", http.StatusOK, "c,3\n"},
+		{"DELETE", "/items?Code=a", "", http.StatusOK, "OK,Delete"},
+		{"DELETE", "/items?Code=a", "", http.StatusNotFound, "ERROR,Not found"},
+		{"GET", "/items", "", http.StatusOK, "b,7\nc,3\n"},
+	} {
+		status, body := send(t, srv, step.method, step.target, step.body, hash)
+		if status != step.status || step.want != "" && body != step.want {
+			t.Fatalf("%s %s: got %d %q, want %d %q", step.method, step.target, status, body, step.status, step.want)
+		}
+	}
+
+	// the records could also be read as JSON objects keyed by the column names
+	req, _ := http.NewRequest("GET", srv.URL+"/items?code=b", nil)
+	req.Header.Set("Accept", "application/json")
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var maps []map[string]interface{}
+	if err = json.NewDecoder(resp.Body).Decode(&maps); err != nil || len(maps) != 1 || maps[0]["Qty"] != float64(7) {
+		t.Fatalf("got %v %v, want the record of b", maps, err)
+	}
+
+	if resp.Header.Get("Content-Schema-Hash") != hash || resp.Header.Get("X-Total-Count") != "1" {
+		t.Fatalf("got headers %v, want the schema fingerprint and the count", resp.Header)
+	}
+}
+
+func TestNewMemoryStoreUnknownKey(t *testing.T) {
+	sch := testSchema()
+	sch.PrimaryKey = []string{"Code", "Missing"}
+
+	if _, err := NewMemoryStore(sch); err == nil || !strings.Contains(err.Error(), "Missing") {
+		t.Fatalf("got %v, want the unknown key column reported", err)
+	}
+}
@@ -0,0 +1,116 @@
+// Package webcsvhttp - net/http helpers that validate CSV request bodies against a webcsv schema
+package webcsvhttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	webcsv "webcsv/lib"
+)
+
+// contextKey - type of the keys of the values stored in the request context
+type contextKey int
+
+const (
+	recordsKey contextKey = iota
+	schemaKey
+)
+
+// MaxBodyBytes - largest request body ValidateMiddleware accepts. The limit also applies to the
+// decompressed data of gzip compressed bodies, unless the schema has a lower MaxBytes.
+var MaxBodyBytes int64 = 10 << 20
+
+// ValidateMiddleware - validates the body of POST, PUT and PATCH requests against the schema and calls
+// next with the records in the request context. Other requests are passed to next as they are.
+//
+// The client could send its schema in the Content-Schema header or its fingerprint in the
+// Content-Schema-Hash header. A schema that does not match sch is rejected with 409 Conflict.
+// The body is always validated by sch, so its settings that are not in the schema string, like the
+// primary key and the limits, are enforced. Invalid schemas and data, and bodies without data records, are
+// rejected with 400 Bad Request. The error responses are in CSV format (ERROR,message).
+func ValidateMiddleware(sch *webcsv.Schema, next http.Handler) http.Handler {
+
+	hash := sch.Fingerprint()
+
+	// a small compressed body could expand to much more than the body limit
+	vsch := sch
+	if sch.MaxBytes <= 0 || sch.MaxBytes > MaxBodyBytes {
+		vsch = sch.Clone()
+		vsch.MaxBytes = MaxBodyBytes
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Method != "POST" && r.Method != "PUT" && r.Method != "PATCH" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if h := strings.TrimSpace(r.Header.Get("Content-Schema-Hash")); h != "" {
+			if h != hash {
+				WriteError(w, http.StatusConflict, "Invalid schema")
+				return
+			}
+		} else if raw := strings.TrimSpace(r.Header.Get("Content-Schema")); raw != "" {
+			csch, err := webcsv.ParseSchemaCached(raw)
+			if err != nil {
+				WriteError(w, http.StatusBadRequest, fmt.Sprintf("Parse: %v", err))
+				return
+			}
+
+			if !sch.IsValid(csch) {
+				WriteError(w, http.StatusConflict, "Invalid schema")
+				return
+			}
+		}
+
+		if r.Body == nil {
+			WriteError(w, http.StatusBadRequest, "No data found")
+			return
+		}
+		defer r.Body.Close()
+
+		// The body could be gzip compressed
+		recs, err := vsch.ValidateReader(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, fmt.Sprintf("Data did not pass the validation against schema: %v", err))
+			return
+		}
+
+		// the header is not a data record
+		if sch.WithHeader && !sch.OmitHeader && len(recs) > 0 {
+			recs = recs[1:]
+		}
+
+		if len(recs) == 0 {
+			WriteError(w, http.StatusBadRequest, "No data found")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), recordsKey, recs)
+		ctx = context.WithValue(ctx, schemaKey, sch)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Records - returns the validated data records of the request. The header is not included.
+// It returns false if the request was not validated by ValidateMiddleware.
+func Records(r *http.Request) ([][]string, bool) {
+	recs, ok := r.Context().Value(recordsKey).([][]string)
+	return recs, ok
+}
+
+// Schema - returns the schema the request body was validated by
+func Schema(r *http.Request) (*webcsv.Schema, bool) {
+	sch, ok := r.Context().Value(schemaKey).(*webcsv.Schema)
+	return sch, ok
+}
+
+// WriteError - writes the error message in CSV format with the status code
+func WriteError(w http.ResponseWriter, status int, msg string) {
+	w.WriteHeader(status)
+	w.Write([]byte("ERROR," + msg))
+}
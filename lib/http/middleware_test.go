@@ -0,0 +1,109 @@
+package webcsvhttp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	webcsv "webcsv/lib"
+)
+
+// testSchema - returns a schema keyed by its first column
+func testSchema() *webcsv.Schema {
+	sch := &webcsv.Schema{Version: "1.0", Delimiter: ","}
+	sch.Columns = []webcsv.SchemaColumn{
+		{Name: "Code", Type: "string", Length: 10},
+		{Name: "Qty", Type: "int"},
+	}
+	sch.PrimaryKey = []string{"Code"}
+	return sch
+}
+
+// validate - sends the body through the middleware and returns the response with the validated records
+func validate(sch *webcsv.Schema, body io.Reader, hdr map[string]string) (*httptest.ResponseRecorder, [][]string) {
+	var recs [][]string
+	h := ValidateMiddleware(sch, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recs, _ = Records(r)
+	}))
+
+	req := httptest.NewRequest("POST", "/", body)
+	for k, v := range hdr {
+		req.Header.Set(k, v)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w, recs
+}
+
+func TestValidateMiddlewareContentSchema(t *testing.T) {
+	sch := testSchema()
+	hdr := map[string]string{"Content-Schema": sch.PrintSchema()}
+
+	w, recs := validate(sch, strings.NewReader("a,1\nb,2\n"), hdr)
+	if w.Code != http.StatusOK || len(recs) != 2 {
+		t.Fatalf("got %d %q with %d records, want 200 with 2", w.Code, w.Body.String(), len(recs))
+	}
+
+	// the key of the server schema is not in the schema string, but it is still enforced
+	w, _ = validate(sch, strings.NewReader("a,1\na,2\n"), hdr)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("got %d %q, want 400 for a repeated key", w.Code, w.Body.String())
+	}
+}
+
+func TestValidateMiddlewareBodyLimit(t *testing.T) {
+	defer func(n int64) { MaxBodyBytes = n }(MaxBodyBytes)
+	MaxBodyBytes = 1 << 10
+
+	sch := testSchema()
+	hdr := map[string]string{"Content-Schema-Hash": sch.Fingerprint()}
+
+	var sb strings.Builder
+	for i := 0; i < 1<<10; i++ {
+		fmt.Fprintf(&sb, "%d,1\n", i)
+	}
+	body := sb.String()
+	if w, _ := validate(sch, strings.NewReader(body), hdr); w.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want 400 for a body too large", w.Code)
+	}
+
+	// the compressed body is below the limit, but the data is not
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte("a,1"))
+	zw.Write(bytes.Repeat([]byte("\n"), 1<<12))
+	zw.Close()
+
+	if int64(buf.Len()) >= MaxBodyBytes {
+		t.Fatalf("compressed body of %d bytes is not below the limit", buf.Len())
+	}
+
+	if w, _ := validate(sch, &buf, hdr); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "maximum") {
+		t.Fatalf("got %d %q, want 400 for decompressed data too large", w.Code, w.Body.String())
+	}
+}
+
+func TestValidateMiddlewareNoData(t *testing.T) {
+	sch := testSchema()
+	hdr := map[string]string{"Content-Schema-Hash": sch.Fingerprint()}
+
+	for _, body := range []string{"", "\n", " \n"} {
+		w, _ := validate(sch, strings.NewReader(body), hdr)
+		if w.Code != http.StatusBadRequest || w.Body.String() != "ERROR,No data found" {
+			t.Errorf("%q: got %d %q, want 400 for no data", body, w.Code, w.Body.String())
+		}
+	}
+
+	// a header alone is no data either
+	sch.WithHeader = true
+	hdr["Content-Schema-Hash"] = sch.Fingerprint()
+	if w, _ := validate(sch, strings.NewReader("Code,Qty\n"), hdr); w.Code != http.StatusBadRequest {
+		t.Errorf("got %d %q, want 400 for a header without data", w.Code, w.Body.String())
+	}
+}
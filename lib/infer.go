@@ -0,0 +1,185 @@
+package webcsv
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// InferOptions - options of InferSchema
+type InferOptions struct {
+	SampleSize int    // number of data records to sample. Zero samples every record.
+	WithHeader bool   // the first record has the column names
+	Delimiter  string // delimiter of the data. Empty defaults to comma.
+	Version    string // version of the inferred schema
+}
+
+// inferColumn - what is known so far of a column from the sampled values
+type inferColumn struct {
+	isInt, isDecimal, isBool, isDate, isDateTime bool
+
+	nullable bool
+	length   int // maximum length in characters
+	whole    int // maximum whole number digits
+	decimals int // maximum decimal digits
+	nonEmpty int // number of values that are not empty
+}
+
+// InferSchema - guesses the schema of the data from a sample of its records. Each column gets the
+// narrowest type that all of its sampled values fit: int, decimal, bool, date, datetime or else string.
+// Columns with empty values are nullable. The result is meant to be reviewed and edited.
+func InferSchema(data []byte, opts InferOptions) (*Schema, error) {
+
+	sch := &Schema{
+		Version:    opts.Version,
+		WithHeader: opts.WithHeader,
+		Delimiter:  opts.Delimiter,
+	}
+	if sch.Delimiter == "" {
+		sch.Delimiter = ","
+	}
+
+	r, err := sch.newReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	r.FieldsPerRecord = -1
+
+	var (
+		header []string
+		cols   []*inferColumn
+	)
+
+	for n := 0; opts.SampleSize <= 0 || n < opts.SampleSize; {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if opts.WithHeader && header == nil {
+			header = rec
+			continue
+		}
+
+		for len(cols) < len(rec) {
+			cols = append(cols, &inferColumn{isInt: true, isDecimal: true, isBool: true, isDate: true, isDateTime: true})
+		}
+
+		for cn, cv := range rec {
+			cols[cn].sample(cv)
+		}
+
+		n++
+	}
+
+	if len(cols) == 0 {
+		return nil, errors.New(`No data to infer the schema from`)
+	}
+
+	sch.Columns = make([]SchemaColumn, len(cols))
+	for cn, ic := range cols {
+		sc := ic.column()
+		sc.Name = fmt.Sprintf("Column%d", cn+1)
+		if cn < len(header) && strings.TrimSpace(header[cn]) != "" {
+			sc.Name = strings.TrimSpace(header[cn])
+		}
+		sch.Columns[cn] = sc
+	}
+
+	sch.isloaded = true
+
+	return sch, nil
+}
+
+// sample - narrows down the possible types of the column by a value
+func (ic *inferColumn) sample(cv string) {
+
+	if cv == "" {
+		ic.nullable = true
+		return
+	}
+	ic.nonEmpty++
+
+	if l := utf8.RuneCountInString(cv); l > ic.length {
+		ic.length = l
+	}
+
+	if ic.isInt {
+		if _, err := strconv.ParseInt(cv, 10, 64); err != nil {
+			ic.isInt = false
+		}
+	}
+
+	if ic.isDecimal {
+		whl, dec, ok := splitDecimal(cv)
+		if ok {
+			if len(whl) > ic.whole {
+				ic.whole = len(whl)
+			}
+			if len(dec) > ic.decimals {
+				ic.decimals = len(dec)
+			}
+		} else {
+			ic.isDecimal = false
+		}
+	}
+
+	if ic.isBool {
+		if _, err := strconv.ParseBool(cv); err != nil {
+			ic.isBool = false
+		}
+	}
+
+	if ic.isDate {
+		if _, err := time.Parse("2006-01-02", cv); err != nil {
+			ic.isDate = false
+		}
+	}
+
+	if ic.isDateTime {
+		if _, err := time.Parse(time.RFC3339, cv); err != nil {
+			ic.isDateTime = false
+		}
+	}
+}
+
+// column - returns the column definition of the narrowest type that fits the sampled values
+func (ic *inferColumn) column() SchemaColumn {
+
+	sc := SchemaColumn{Nullable: ic.nullable}
+
+	switch {
+	case ic.nonEmpty == 0:
+		// no values to infer from
+		sc.Type = "string"
+		sc.Length = 4000
+	case ic.isInt:
+		sc.Type = "int"
+	case ic.isDecimal:
+		sc.Type = "decimal"
+		sc.Precision = ic.whole + ic.decimals
+		sc.Scale = ic.decimals
+		if sc.Precision == 0 {
+			sc.Precision = 1
+		}
+	case ic.isBool:
+		sc.Type = "bool"
+	case ic.isDate:
+		sc.Type = "date"
+	case ic.isDateTime:
+		sc.Type = "datetime"
+	default:
+		sc.Type = "string"
+		sc.Length = ic.length
+	}
+
+	return sc
+}
@@ -0,0 +1,129 @@
+package webcsv
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// schemaJSON - has the same fields of Schema without its methods to avoid recursion
+type schemaJSON Schema
+
+// MarshalJSON - implements json.Marshaler
+func (sch *Schema) MarshalJSON() ([]byte, error) {
+	return json.Marshal((*schemaJSON)(sch))
+}
+
+// UnmarshalJSON - implements json.Unmarshaler
+func (sch *Schema) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*schemaJSON)(sch)); err != nil {
+		return err
+	}
+
+	// It makes no sense of the schema does not contain columns
+	sch.isloaded = len(sch.Columns) > 0
+
+	return nil
+}
+
+// ToJSONSchema - returns a JSON Schema describing the data as an array of objects keyed by column name
+func (sch *Schema) ToJSONSchema() ([]byte, error) {
+
+	props := make(map[string]interface{}, len(sch.Columns))
+	required := make([]string, 0, len(sch.Columns))
+
+	for cn := range sch.Columns {
+		sc := &sch.Columns[cn]
+
+		prop, err := sc.jsonSchema()
+		if err != nil {
+			return nil, err
+		}
+		props[sc.Name] = prop
+
+		if !sc.Nullable {
+			required = append(required, sc.Name)
+		}
+	}
+
+	js := map[string]interface{}{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"type":    "array",
+		"items": map[string]interface{}{
+			"type":       "object",
+			"properties": props,
+			"required":   required,
+		},
+	}
+
+	if sch.Version != "" {
+		js["$comment"] = "WebCSV schema version " + sch.Version
+	}
+
+	return json.MarshalIndent(js, "", "  ")
+}
+
+// jsonSchema - JSON Schema of the column values
+func (sc *SchemaColumn) jsonSchema() (map[string]interface{}, error) {
+
+	prop := make(map[string]interface{})
+	typ := "string"
+
+	switch sc.Type {
+	case "string":
+		prop["maxLength"] = sc.Length
+	case "email":
+		prop["format"] = "email"
+		if sc.Length > 0 {
+			prop["maxLength"] = sc.Length
+		}
+	case "int":
+		typ = "integer"
+		// the range of the bit width, which a range of the column replaces
+		if sc.Bits != 0 {
+			prop["minimum"] = int64(-1) << (sc.Bits - 1)
+			prop["maximum"] = int64(1)<<(sc.Bits-1) - 1
+		}
+	case "decimal":
+		typ = "number"
+		// the whole number digits are limited by the precision less the scale
+		limit := math.Pow10(sc.Precision - sc.Scale)
+		prop["exclusiveMinimum"] = -limit
+		prop["exclusiveMaximum"] = limit
+		prop["multipleOf"] = math.Pow10(-sc.Scale)
+	case "bool":
+		typ = "boolean"
+	case "date":
+		prop["format"] = "date"
+	case "datetime":
+		prop["format"] = "date-time"
+	case "time":
+		prop["format"] = "time"
+	case "uuid":
+		prop["format"] = "uuid"
+	case "enum":
+		prop["enum"] = sc.Choices
+	default:
+		return nil, fmt.Errorf("column type %q of column %q has no JSON Schema type", sc.Type, sc.Name)
+	}
+
+	if sc.Min != nil {
+		prop["minimum"] = *sc.Min
+	}
+	if sc.Max != nil {
+		prop["maximum"] = *sc.Max
+	}
+	if sc.Pattern != "" {
+		prop["pattern"] = "^(?:" + sc.Pattern + ")$"
+	}
+	if sc.Default != "" {
+		prop["default"] = sc.Default
+	}
+
+	prop["type"] = typ
+	if sc.Nullable {
+		prop["type"] = []string{typ, "null"}
+	}
+
+	return prop, nil
+}
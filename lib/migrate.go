@@ -0,0 +1,129 @@
+package webcsv
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Migration - converts the data records of one schema version to the records of another version
+type Migration func(records [][]string) ([][]string, error)
+
+// Migrator - keeps the migrations between schema versions. A migrator is safe for concurrent use.
+type Migrator struct {
+	mu         sync.RWMutex
+	migrations map[string]map[string]Migration // migrations by from and to version
+}
+
+// NewMigrator - creates an empty migrator
+func NewMigrator() *Migrator {
+	return &Migrator{
+		migrations: make(map[string]map[string]Migration),
+	}
+}
+
+// Register - adds the migration of the records from one schema version to another. A migration registered
+// for the same versions is replaced.
+func (m *Migrator) Register(from, to string, fn Migration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.migrations == nil {
+		m.migrations = make(map[string]map[string]Migration)
+	}
+
+	if m.migrations[from] == nil {
+		m.migrations[from] = make(map[string]Migration)
+	}
+	m.migrations[from][to] = fn
+}
+
+// path - returns the migrations that convert the records from one version to another. A direct migration
+// is preferred. Otherwise, the shortest chain of registered migrations (1.0 to 1.1 to 2.0) is used.
+func (m *Migrator) path(from, to string) []Migration {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	type step struct {
+		version string
+		fns     []Migration
+	}
+
+	visited := map[string]bool{from: true}
+	queue := []step{{version: from}}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+
+		if fn, ok := m.migrations[s.version][to]; ok {
+			return append(s.fns, fn)
+		}
+
+		for v, fn := range m.migrations[s.version] {
+			if visited[v] {
+				continue
+			}
+			visited[v] = true
+
+			fns := append(append([]Migration(nil), s.fns...), fn)
+			queue = append(queue, step{version: v, fns: fns})
+		}
+	}
+
+	return nil
+}
+
+// Migrate - validates the data against the older schema, converts the records by the migrations of the
+// schema Migrator and validates the converted records against this schema. The header is not migrated,
+// so only the data records are returned.
+func (sch *Schema) Migrate(from *Schema, data []byte) ([][]string, error) {
+
+	if from == nil {
+		return nil, fmt.Errorf("Migrate requires the schema of the data")
+	}
+
+	if sch.Migrator == nil {
+		return nil, fmt.Errorf("schema %s has no migrator", sch.Version)
+	}
+
+	recs, err := from.ValidateReturn(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// the header is not migrated
+	if from.WithHeader && !from.OmitHeader && len(recs) > 0 {
+		recs = recs[1:]
+	}
+
+	// the data is already in this version
+	if from.Version != sch.Version {
+		fns := sch.Migrator.path(from.Version, sch.Version)
+		if fns == nil {
+			return nil, fmt.Errorf("no migration from version %s to %s", from.Version, sch.Version)
+		}
+
+		for _, fn := range fns {
+			if recs, err = fn(recs); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// The migrated records are validated as if they were read from the data
+	cc := sch.compiled()
+
+	var errs []ValidationError
+	for i, rec := range recs {
+		errs = append(errs, sch.validateRecord(cc, i+1, rec, len(rec), nil)...)
+
+		if len(errs) > 0 && sch.FailFast {
+			break
+		}
+	}
+
+	if err = joinErrors(errs); err != nil {
+		return nil, err
+	}
+
+	return recs, nil
+}
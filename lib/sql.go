@@ -0,0 +1,110 @@
+package webcsv
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ToSQL - returns the CREATE TABLE statement of the schema for the dialect: postgres or mysql.
+// Columns that are not nullable are NOT NULL.
+func (sch *Schema) ToSQL(table string, dialect string) (string, error) {
+
+	var quote func(string) string
+	switch strings.ToLower(dialect) {
+	case "postgres", "postgresql":
+		dialect = "postgres"
+		quote = func(n string) string { return `"` + strings.ReplaceAll(n, `"`, `""`) + `"` }
+	case "mysql":
+		dialect = "mysql"
+		quote = func(n string) string { return "`" + strings.ReplaceAll(n, "`", "``") + "`" }
+	default:
+		return "", fmt.Errorf("unsupported SQL dialect %q", dialect)
+	}
+
+	if len(sch.Columns) == 0 {
+		return "", fmt.Errorf("schema has no columns")
+	}
+
+	cols := make([]string, len(sch.Columns))
+	for cn := range sch.Columns {
+		sc := &sch.Columns[cn]
+
+		typ, err := sc.sqlType(dialect)
+		if err != nil {
+			return "", err
+		}
+
+		cols[cn] = "\t" + quote(sc.Name) + " " + typ
+		if !sc.Nullable {
+			cols[cn] += " NOT NULL"
+		}
+	}
+
+	return "CREATE TABLE " + quote(table) + " (\n" + strings.Join(cols, ",\n") + "\n);", nil
+}
+
+// sqlType - SQL type of the column in the dialect
+func (sc *SchemaColumn) sqlType(dialect string) (string, error) {
+
+	switch sc.Type {
+	case "string":
+		return fmt.Sprintf("VARCHAR(%d)", sc.Length), nil
+	case "email":
+		if sc.Length > 0 {
+			return fmt.Sprintf("VARCHAR(%d)", sc.Length), nil
+		}
+		return "VARCHAR(254)", nil
+	case "int":
+		switch sc.Bits {
+		case 8:
+			if dialect == "mysql" {
+				return "TINYINT", nil
+			}
+			return "SMALLINT", nil
+		case 16:
+			return "SMALLINT", nil
+		case 32:
+			return "INTEGER", nil
+		}
+
+		// int without bits is validated as 64-bit
+		return "BIGINT", nil
+	case "decimal":
+		return fmt.Sprintf("DECIMAL(%d,%d)", sc.Precision, sc.Scale), nil
+	case "bool":
+		return "BOOLEAN", nil
+	case "date":
+		return "DATE", nil
+	case "datetime":
+		if dialect == "mysql" {
+			return "DATETIME", nil
+		}
+		return "TIMESTAMP", nil
+	case "time":
+		return "TIME", nil
+	case "uuid":
+		if dialect == "postgres" {
+			return "UUID", nil
+		}
+		return "CHAR(36)", nil
+	case "enum":
+		if dialect == "mysql" {
+			choices := make([]string, len(sc.Choices))
+			for i, c := range sc.Choices {
+				choices[i] = "'" + strings.ReplaceAll(c, "'", "''") + "'"
+			}
+			return "ENUM(" + strings.Join(choices, ",") + ")", nil
+		}
+
+		// the longest choice fits the column
+		l := 1
+		for _, c := range sc.Choices {
+			if len(c) > l {
+				l = len(c)
+			}
+		}
+		return fmt.Sprintf("VARCHAR(%d)", l), nil
+	}
+
+	return "", fmt.Errorf("column type %q of column %q has no SQL type", sc.Type, sc.Name)
+}
@@ -0,0 +1,24 @@
+package webcsv
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToSQLIntBits(t *testing.T) {
+	sch, err := ParseSchema("ver:1.0; A:int,B:int64,C:int32,D:int16,E:int8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ddl, err := sch.ToSQL("t", "postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{`"A" BIGINT`, `"B" BIGINT`, `"C" INTEGER`, `"D" SMALLINT`, `"E" SMALLINT`} {
+		if !strings.Contains(ddl, want) {
+			t.Errorf("got %s, want %s", ddl, want)
+		}
+	}
+}
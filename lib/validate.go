@@ -0,0 +1,1256 @@
+package webcsv
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidationError - a validation failure of a line or a column value. Its Kind is one of parse, header,
+// columns, required, length, type, precision, range, pattern, unit, validator or duplicate.
+type ValidationError struct {
+	Line       int    // physical line where the record starts, starting from 1
+	Column     int    // column index, or -1 if the error is about the whole line
+	ColumnName string // name of the column in the schema
+	Value      string // value that failed the validation
+	Kind       string // kind of error, listed above
+	Err        error  // description of the error
+}
+
+// Error - implements the error interface
+func (ve ValidationError) Error() string {
+	return ve.Err.Error()
+}
+
+// Unwrap - returns the underlying error
+func (ve ValidationError) Unwrap() error {
+	return ve.Err
+}
+
+// ValidationErrors - list of validation errors
+type ValidationErrors []ValidationError
+
+// Error - implements the error interface. Each error is on its own line.
+func (ves ValidationErrors) Error() string {
+	errorstr := ""
+	for _, ve := range ves {
+		errorstr += ve.Error() + "\n"
+	}
+	return errorstr
+}
+
+// newReader - creates a CSV reader configured by the schema
+func (sch *Schema) newReader(rd io.Reader) (*csv.Reader, error) {
+
+	comma, err := sch.comma()
+	if err != nil {
+		return nil, err
+	}
+
+	// The delimiter would split decimals that use it as separator
+	if sch.DecimalSeparator != "" && strings.ContainsRune(sch.DecimalSeparator, comma) ||
+		sch.ThousandsSeparator != "" && strings.ContainsRune(sch.ThousandsSeparator, comma) {
+		return nil, fmt.Errorf("Delimiter %q collides with the decimal or thousands separator", string(comma))
+	}
+
+	// The decimal point is the default decimal separator
+	dec := sch.DecimalSeparator
+	if dec == "" {
+		dec = "."
+	}
+
+	if dec == sch.ThousandsSeparator {
+		return nil, fmt.Errorf("Decimal and thousands separators are both %q", dec)
+	}
+
+	if rd, err = sch.input(rd); err != nil {
+		return nil, err
+	}
+
+	// Escaped values are converted to quoted values
+	if sch.BackslashEscape {
+		rd = newEscapeReader(rd, comma, sch.CommentChar)
+	}
+
+	r := csv.NewReader(rd)
+	r.Comma = comma
+	r.Comment = sch.CommentChar
+	r.LazyQuotes = sch.LazyQuotes
+	r.FieldsPerRecord = sch.FieldsPerRecord
+
+	// The column count will be checked against the schema instead of the first record
+	if sch.StrictColumnCount {
+		r.FieldsPerRecord = -1
+	}
+
+	return r, nil
+}
+
+// recordReader - reads the records of the data with the physical line where each record starts.
+// A last record of a single whitespace field, as left by a blank line at the end of the data, is skipped
+// unless the schema has StrictTrailingRow set. Records of several empty fields are data.
+type recordReader struct {
+	r    recordSource
+	skip bool
+
+	// record read ahead to tell if a blank record is the last one
+	held     bool
+	heldRec  []string
+	heldLine int
+	heldErr  error
+}
+
+// newRecordReader - creates the reader of the records from the source
+func (sch *Schema) newRecordReader(r recordSource) *recordReader {
+	return &recordReader{r: r, skip: !sch.StrictTrailingRow}
+}
+
+// read - reads the next record from the source
+func (rr *recordReader) read() ([]string, int, error) {
+	rec, err := rr.r.Read()
+	if err != nil {
+		return rec, 0, err
+	}
+
+	line, _ := rr.r.FieldPos(0)
+	return rec, line, nil
+}
+
+func (rr *recordReader) Read() ([]string, int, error) {
+	if rr.held {
+		rr.held = false
+		return rr.heldRec, rr.heldLine, rr.heldErr
+	}
+
+	rec, line, err := rr.read()
+	if !rr.skip || !isBlank(rec) || err != nil && !errors.Is(err, csv.ErrFieldCount) {
+		return rec, line, err
+	}
+
+	// A blank record with fewer fields has no line position. Its line is the one of the error.
+	var pe *csv.ParseError
+	if errors.As(err, &pe) {
+		line = pe.Line
+	}
+
+	// the record could be reused by the next read
+	rec = append([]string(nil), rec...)
+
+	next, nline, nerr := rr.read()
+	if nerr == io.EOF {
+		return nil, 0, io.EOF
+	}
+
+	rr.held, rr.heldRec, rr.heldLine, rr.heldErr = true, next, nline, nerr
+
+	return rec, line, err
+}
+
+// isBlank - checks if the record is a blank line, a single field of whitespace
+func isBlank(rec []string) bool {
+	return len(rec) == 1 && strings.TrimSpace(rec[0]) == ""
+}
+
+// LimitError - the input exceeds the maximum rows or bytes of the schema
+type LimitError struct {
+	Limit int64
+	Unit  string // rows or bytes
+}
+
+func (le *LimitError) Error() string {
+	return fmt.Sprintf("input exceeds maximum of %d %s", le.Limit, le.Unit)
+}
+
+// limitReader - reader that fails with a LimitError once more than max bytes are read
+type limitReader struct {
+	rd   io.Reader
+	left int64
+	max  int64
+}
+
+func (lr *limitReader) Read(p []byte) (int, error) {
+	// one byte more than the limit is read to tell an input of exactly the limit from a larger one
+	if int64(len(p)) > lr.left+1 {
+		p = p[0 : lr.left+1]
+	}
+
+	n, err := lr.rd.Read(p)
+	lr.left -= int64(n)
+	if lr.left < 0 {
+		return 0, &LimitError{Limit: lr.max, Unit: "bytes"}
+	}
+
+	return n, err
+}
+
+// input - limits and transcodes the data before it is parsed
+func (sch *Schema) input(rd io.Reader) (io.Reader, error) {
+
+	// The limit applies to the decompressed data before it is transcoded
+	if sch.MaxBytes > 0 {
+		rd = &limitReader{rd: rd, left: sch.MaxBytes, max: sch.MaxBytes}
+	}
+
+	// The input is parsed and validated in UTF-8
+	return sch.decodeCharset(rd)
+}
+
+// parseError - converts a CSV reader error to a validation error
+func parseError(err error) ValidationError {
+	ve := ValidationError{Column: -1, Kind: "parse", Err: err}
+	var pe *csv.ParseError
+	if errors.As(err, &pe) {
+		ve.Line = pe.Line
+	}
+	return ve
+}
+
+// Validate - validates the data by the schema and returns the records with the validation errors found.
+// Validation stops at the first line with errors if FailFast is set.
+func (sch *Schema) Validate(data []byte) (Records [][]string, Errors []ValidationError) {
+	return sch.validate(bytes.NewReader(data))
+}
+
+// ValidateReader - validates the data from r like ValidateReturn. Gzip compressed data is detected by its
+// magic bytes and decompressed while it is read.
+func (sch *Schema) ValidateReader(rd io.Reader) (Records [][]string, Error error) {
+
+	rd, Error = GzipReader(rd)
+	if Error != nil {
+		return
+	}
+
+	var errs []ValidationError
+	Records, errs = sch.validate(rd)
+	Error = joinErrors(errs)
+
+	return
+}
+
+// GzipReader - returns a reader that decompresses r if it starts with the gzip magic bytes. Otherwise,
+// the data of r is read as it is.
+func GzipReader(rd io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(rd)
+
+	magic, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(br)
+	}
+
+	return br, nil
+}
+
+// validate - validates all the data from the reader
+func (sch *Schema) validate(rd io.Reader) (Records [][]string, Errors []ValidationError) {
+	Records, _, _, Errors = sch.validateMapped(rd)
+	return
+}
+
+// validateMapped - validates all the data from the reader like validate. It also returns the physical line
+// where each record starts and the position of each schema column in the input, -1 for columns missing from
+// the header, or nil if the input is in schema order.
+func (sch *Schema) validateMapped(rd io.Reader) (Records [][]string, Lines []int, Positions []int, Errors []ValidationError) {
+
+	// Data will be parsed as CSV, or cut by position in fixed mode
+	r, err := sch.newSource(rd)
+	if err != nil {
+		Errors = append(Errors, ValidationError{Column: -1, Kind: "parse", Err: err})
+		return
+	}
+
+	// The physical line where each record starts is kept, since quoted values can span several lines
+	rr := sch.newRecordReader(r)
+	for {
+		rec, line, err := rr.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			Errors = append(Errors, parseError(err))
+			Records, Lines = nil, nil
+			return
+		}
+
+		Records = append(Records, rec)
+		Lines = append(Lines, line)
+
+		if sch.MaxRows > 0 && sch.rows(len(Records)) > sch.MaxRows {
+			Errors = append(Errors, ValidationError{Line: line, Column: -1, Kind: "parse", Err: &LimitError{Limit: int64(sch.MaxRows), Unit: "rows"}})
+			Records, Lines = nil, nil
+			return
+		}
+	}
+
+	// The first record is the header. It is not validated as data.
+	first := 0
+	pos := sch.indexPositions() // position of the schema columns in the input
+	if sch.WithHeader && len(Records) > 0 {
+		first = 1
+
+		if sch.VerifyHeader {
+			if ve := sch.validateHeader(Lines[0], sch.indexedHeader(Records[0], pos)); ve != nil {
+				Errors = append(Errors, *ve)
+				return
+			}
+		}
+
+		if sch.MapByHeader {
+			pos = sch.headerPositions(Records[0])
+		}
+	}
+
+	// The records are arranged in the order of the schema columns. The number of fields of the input is
+	// kept for the column count check.
+	fields := make([]int, len(Records))
+	for i := range Records {
+		fields[i] = len(Records[i])
+		if pos != nil {
+			Records[i] = reorder(Records[i], pos, nil)
+		}
+	}
+
+	keys, err := sch.newKeyTracker()
+	if err != nil {
+		Errors = append(Errors, ValidationError{Column: -1, Kind: "parse", Err: err})
+		Records, Lines = nil, nil
+		return
+	}
+
+	cc := sch.compiled()
+
+	// Validate each line and column
+	for i, rec := range Records {
+
+		if i < first {
+			continue
+		}
+
+		Errors = append(Errors, sch.validateRecord(cc, Lines[i], rec, fields[i], pos)...)
+
+		if ve := keys.check(Lines[i], rec); ve != nil {
+			Errors = append(Errors, *ve)
+		}
+
+		if len(Errors) > 0 && sch.FailFast {
+			break
+		}
+	}
+
+	if sch.OmitHeader {
+		Records, Lines = Records[first:], Lines[first:]
+	}
+
+	Positions = pos
+
+	return
+}
+
+// ValidateStream - validates the data from r one record at a time and calls fn for each valid data record.
+// The header is not passed to fn. The record slice is reused and is only valid during the call to fn.
+// Invalid records are not passed to fn and their errors are returned as ValidationErrors after the whole
+// input is read, or at the first invalid record if FailFast is set. An error returned by fn stops the
+// validation and is returned as is.
+func (sch *Schema) ValidateStream(rd io.Reader, fn func(record []string) error) error {
+	return sch.ValidateStreamContext(context.Background(), rd, fn)
+}
+
+// contextCheckRows - number of records read between checks of the context
+const contextCheckRows = 1000
+
+// ValidateStreamContext - validates the data from r like ValidateStream. The context is checked every
+// few records, and the context error is returned once it is cancelled or its deadline is exceeded.
+func (sch *Schema) ValidateStreamContext(ctx context.Context, rd io.Reader, fn func(record []string) error) error {
+
+	r, err := sch.newSource(rd)
+	if err != nil {
+		return err
+	}
+
+	// the records of the CSV reader are only used until the next one is read
+	if cr, ok := r.(*csv.Reader); ok {
+		cr.ReuseRecord = true
+	}
+	rr := sch.newRecordReader(r)
+
+	keys, err := sch.newKeyTracker()
+	if err != nil {
+		return err
+	}
+
+	cc := sch.compiled()
+
+	var (
+		errs ValidationErrors
+		pos  = sch.indexPositions() // position of the schema columns in the input
+		buf  []string               // record arranged in schema order
+	)
+	if pos != nil {
+		buf = make([]string, len(sch.Columns))
+	}
+
+	for n, first := 0, true; ; n, first = n+1, false {
+		if n%contextCheckRows == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		rec, line, err := rr.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			var le *LimitError
+			if errors.As(err, &le) {
+				return err
+			}
+			return append(errs, parseError(err))
+		}
+
+		if sch.MaxRows > 0 && sch.rows(n+1) > sch.MaxRows {
+			return &LimitError{Limit: int64(sch.MaxRows), Unit: "rows"}
+		}
+
+		// The first record is the header. It is not validated as data.
+		if first && sch.WithHeader {
+			if sch.VerifyHeader {
+				if ve := sch.validateHeader(line, sch.indexedHeader(rec, pos)); ve != nil {
+					return append(errs, *ve)
+				}
+			}
+
+			if sch.MapByHeader {
+				pos = sch.headerPositions(rec)
+				buf = make([]string, len(sch.Columns))
+			}
+			continue
+		}
+
+		fields := len(rec)
+		if pos != nil {
+			rec = reorder(rec, pos, buf)
+		}
+
+		verrs := sch.validateRecord(cc, line, rec, fields, pos)
+		if ve := keys.check(line, rec); ve != nil {
+			verrs = append(verrs, *ve)
+		}
+
+		if len(verrs) > 0 {
+			errs = append(errs, verrs...)
+			if sch.FailFast {
+				break
+			}
+			continue
+		}
+
+		if err = fn(rec); err != nil {
+			return err
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// Count - validates the data and returns the number of valid data records, not counting the header.
+// The records are streamed, so the whole records are not kept in memory.
+func (sch *Schema) Count(data []byte) (int, error) {
+	cnt := 0
+	err := sch.ValidateStream(bytes.NewReader(data), func([]string) error {
+		cnt++
+		return nil
+	})
+	return cnt, err
+}
+
+// QualityReport - validates the whole data and returns the number of values that failed the validation by
+// column name. The validation goes on past the first invalid record even if FailFast is set. Columns
+// without failures are reported with zero. Failures of whole lines, like duplicate keys, are not counted. An
+// error is returned if the data could not be read to the end or the header is not valid.
+func (sch *Schema) QualityReport(data []byte) (map[string]int, error) {
+
+	cs := sch.Clone()
+	cs.FailFast = false
+
+	report := make(map[string]int, len(sch.Columns))
+	for _, c := range sch.Columns {
+		report[c.Name] = 0
+	}
+
+	err := cs.ValidateStream(bytes.NewReader(data), func([]string) error {
+		return nil
+	})
+
+	var ves ValidationErrors
+	if err != nil && !errors.As(err, &ves) {
+		return nil, err
+	}
+
+	// a value could fail more than one check, but it is counted once
+	type cell struct{ line, column int }
+	failed := make(map[cell]bool)
+	for _, ve := range ves {
+		if ve.Kind == "parse" || ve.Kind == "header" {
+			return nil, ve
+		}
+
+		// a duplicate key is a failure of the whole line
+		if ve.Kind == "duplicate" || ve.Column < 0 || failed[cell{ve.Line, ve.Column}] {
+			continue
+		}
+		failed[cell{ve.Line, ve.Column}] = true
+
+		report[ve.ColumnName]++
+	}
+
+	return report, nil
+}
+
+// FileError - error opening a file for validation, as opposed to a validation error of its data
+type FileError struct {
+	Path string
+	Err  error
+}
+
+// Error - implements the error interface
+func (fe *FileError) Error() string {
+	return fmt.Sprintf("Could not open file %s. Error: %s", fe.Path, fe.Err.Error())
+}
+
+// Unwrap - returns the underlying error
+func (fe *FileError) Unwrap() error {
+	return fe.Err
+}
+
+// ValidateFile - opens the file and streams its data through validation. It returns the valid data records
+// without the header. A file that could not be opened returns a *FileError, while invalid data returns
+// ValidationErrors.
+func (sch *Schema) ValidateFile(path string) (Records [][]string, Error error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		Error = &FileError{Path: path, Err: err}
+		return
+	}
+	defer f.Close()
+
+	Error = sch.ValidateStream(f, func(rec []string) error {
+		// the record is reused by the stream
+		Records = append(Records, append([]string(nil), rec...))
+		return nil
+	})
+
+	return
+}
+
+// headerPositions - returns the position of each schema column in the header, or -1 if the header
+// does not have the column. Names are matched regardless of case unless HeaderCaseSensitive is set.
+func (sch *Schema) headerPositions(hdr []string) []int {
+	pos := make([]int, len(sch.Columns))
+	for cn := range sch.Columns {
+		pos[cn] = -1
+		for i, h := range hdr {
+			h = strings.TrimSpace(h)
+			if h == sch.Columns[cn].Name || !sch.HeaderCaseSensitive && strings.EqualFold(h, sch.Columns[cn].Name) {
+				pos[cn] = i
+				break
+			}
+		}
+	}
+	return pos
+}
+
+// indexPositions - returns the position of each schema column in the input if any column has an Index.
+// Columns without an Index keep their own position. It returns nil if no column has an Index.
+func (sch *Schema) indexPositions() []int {
+	var pos []int
+	for cn, c := range sch.Columns {
+		if c.Index == nil {
+			continue
+		}
+
+		if pos == nil {
+			pos = make([]int, len(sch.Columns))
+			for i := range pos {
+				pos[i] = i
+			}
+		}
+		pos[cn] = *c.Index
+	}
+	return pos
+}
+
+// indexedHeader - arranges the header by the Index of the columns to be checked against the column names.
+// The header is kept as it is when it is matched by the names.
+func (sch *Schema) indexedHeader(hdr []string, pos []int) []string {
+	if pos == nil || sch.MapByHeader {
+		return hdr
+	}
+	return reorder(hdr, pos, nil)
+}
+
+// reorder - arranges the record in the order of the schema columns. Columns missing
+// from the record are empty. The result is stored in buf if it is not nil.
+func reorder(rec []string, pos []int, buf []string) []string {
+	if buf == nil {
+		buf = make([]string, len(pos))
+	}
+
+	for cn, p := range pos {
+		buf[cn] = ""
+		if p >= 0 && p < len(rec) {
+			buf[cn] = rec[p]
+		}
+	}
+
+	return buf
+}
+
+// HeaderError - differences of a header from the schema column names
+type HeaderError struct {
+	Missing    []string // schema columns that are not in the header
+	Extra      []string // header names that are not schema columns
+	OutOfOrder []string // schema columns that are in the header in a different position
+}
+
+// Error - implements the error interface
+func (he *HeaderError) Error() string {
+	parts := make([]string, 0, 3)
+	if len(he.Missing) > 0 {
+		parts = append(parts, "missing columns: "+strings.Join(he.Missing, ", "))
+	}
+	if len(he.Extra) > 0 {
+		parts = append(parts, "extra columns: "+strings.Join(he.Extra, ", "))
+	}
+	if len(he.OutOfOrder) > 0 {
+		parts = append(parts, "columns out of order: "+strings.Join(he.OutOfOrder, ", "))
+	}
+	return "Header does not match the schema. " + strings.Join(parts, "; ")
+}
+
+// CheckHeader - compares a header against the schema column names and returns a *HeaderError describing
+// the missing, extra and out of order columns. Names are compared regardless of case unless
+// HeaderCaseSensitive is set, and the order is not checked when MapByHeader is set.
+func (sch *Schema) CheckHeader(header []string) error {
+
+	he := &HeaderError{}
+
+	pos := sch.headerPositions(header)
+
+	// header names that are schema columns
+	known := make([]bool, len(header))
+	for cn, p := range pos {
+		if p == -1 {
+			he.Missing = append(he.Missing, sch.Columns[cn].Name)
+			continue
+		}
+		known[p] = true
+	}
+
+	for i, h := range header {
+		if !known[i] {
+			he.Extra = append(he.Extra, strings.TrimSpace(h))
+		}
+	}
+
+	// Columns missing from a header that is mapped by name are fine unless they are required
+	if sch.MapByHeader {
+		missing := he.Missing[:0]
+		for _, n := range he.Missing {
+			if sch.Columns[sch.columnIndex(n)].Required {
+				missing = append(missing, n)
+			}
+		}
+		he.Missing = missing
+	}
+
+	// The present columns must follow each other in the order of the schema
+	if !sch.MapByHeader {
+		present := make([]int, 0, len(pos))
+		for cn, p := range pos {
+			if p != -1 {
+				present = append(present, cn)
+			}
+		}
+
+		order := make([]int, 0, len(pos))
+		for i := range header {
+			for cn, p := range pos {
+				if p == i {
+					order = append(order, cn)
+				}
+			}
+		}
+
+		for i := range present {
+			if present[i] != order[i] {
+				he.OutOfOrder = append(he.OutOfOrder, sch.Columns[order[i]].Name)
+			}
+		}
+
+		// extra columns before the schema columns shift them from their position
+		if len(he.OutOfOrder) == 0 && len(he.Missing) == 0 {
+			for cn, p := range pos {
+				if p != cn {
+					he.OutOfOrder = append(he.OutOfOrder, sch.Columns[cn].Name)
+				}
+			}
+		}
+	}
+
+	if len(he.Missing) > 0 || len(he.Extra) > 0 || len(he.OutOfOrder) > 0 {
+		return he
+	}
+
+	return nil
+}
+
+// rows - returns the number of data records among the first n records read
+func (sch *Schema) rows(n int) int {
+	if sch.WithHeader {
+		return n - 1
+	}
+	return n
+}
+
+// validateHeader - checks if the header names match the schema column names
+func (sch *Schema) validateHeader(line int, hdr []string) *ValidationError {
+	if err := sch.CheckHeader(hdr); err != nil {
+		return &ValidationError{
+			Line:   line,
+			Column: -1,
+			Kind:   "header",
+			Err:    err,
+		}
+	}
+
+	return nil
+}
+
+// length - length of a value in characters, or in bytes if ByteLength is set
+func (sch *Schema) length(v string) int {
+	if sch.ByteLength {
+		return len(v)
+	}
+	return utf8.RuneCountInString(v)
+}
+
+// validateRecord - validates a record against the schema columns. The record is in the order of the columns.
+// When it was arranged by the header or the Index of the columns, pos has the position of each column in the
+// input. fields is the number of fields of the record in the input, before it was arranged.
+func (sch *Schema) validateRecord(cc *columnCache, line int, rec []string, fields int, pos []int) (errs []ValidationError) {
+
+	if sch.StrictColumnCount && fields != len(sch.Columns) {
+		errs = append(errs, ValidationError{
+			Line:   line,
+			Column: -1,
+			Kind:   "columns",
+			Err:    fmt.Errorf("line %d has %d columns, schema defines %d", line, fields, len(sch.Columns)),
+		})
+		return
+	}
+
+	var sc *SchemaColumn
+
+	// invalid - adds a validation error for the current column
+	invalid := func(cn int, cv string, kind string, err error) {
+		errs = append(errs, ValidationError{
+			Line:       line,
+			Column:     cn,
+			ColumnName: sc.Name,
+			Value:      cv,
+			Kind:       kind,
+			Err:        err,
+		})
+	}
+
+	// Fields beyond the schema columns have nothing to be validated against
+	for cn := range sch.Columns {
+
+		sc = &sch.Columns[cn]
+
+		// Columns missing from the input are only checked if they are required
+		if cn >= len(rec) || pos != nil && pos[cn] == -1 {
+			if sc.Required {
+				invalid(cn, "", "required", fmt.Errorf("line %d is missing required column %q", line, sc.Name))
+				continue
+			}
+
+			// a missing column arranged by the header could still take the default
+			if cn >= len(rec) || sc.Default == "" {
+				continue
+			}
+		}
+
+		cv := rec[cn]
+
+		// Trimmed values are kept in the record so that they can be decoded
+		if sch.TrimFields {
+			cv = strings.TrimSpace(cv)
+			rec[cn] = cv
+		}
+
+		// Transformed values are kept in the record like the trimmed ones
+		if len(sc.Transforms) > 0 {
+			cv = transform(cv, sc.Transforms)
+			rec[cn] = cv
+		}
+
+		// Empty values take the default of the column
+		if cv == "" && sc.Default != "" {
+			cv = sc.Default
+			if sch.ApplyDefaults {
+				rec[cn] = cv
+			}
+		}
+
+		// Empty values of nullable columns need no further checking
+		if sc.Nullable && cv == "" {
+			continue
+		}
+
+		// Check if the value matches the pattern
+		if sc.Pattern != "" {
+			if cc.patternErrs[cn] != nil {
+				invalid(cn, cv, "pattern", fmt.Errorf("Column %d of line %d has an %s", cn, line, cc.patternErrs[cn].Error()))
+				continue
+			}
+
+			if !cc.patterns[cn].MatchString(cv) {
+				invalid(cn, cv, "pattern", fmt.Errorf("Column %d of line %d does not match the pattern /%s/", cn, line, sc.Pattern))
+				continue
+			}
+		}
+
+		// The unit must follow the number exactly (42%, 12kg). It is not part of the numeric validation.
+		if sc.Unit != "" {
+			if !strings.HasSuffix(cv, sc.Unit) {
+				invalid(cn, cv, "unit", fmt.Errorf("Column %d of line %d does not end with the unit %s", cn, line, sc.Unit))
+				continue
+			}
+
+			cv = strings.TrimSuffix(cv, sc.Unit)
+			if sch.StripUnits {
+				rec[cn] = cv
+			}
+		}
+
+		switch sc.Type {
+		case "string":
+			// Check if the value exceeds the length
+			if sch.length(cv) > sc.Length {
+				invalid(cn, cv, "length", fmt.Errorf("Column %d of line %d exceeds specified column length of %d", cn, line, sc.Length))
+				continue
+			}
+		case "int":
+			// Check if the value can be converted to int of the column width
+			bits := sc.Bits
+			if bits == 0 {
+				bits = 64
+			}
+
+			n, err := strconv.ParseInt(cv, 10, bits)
+			if errors.Is(err, strconv.ErrRange) {
+				invalid(cn, cv, "range", fmt.Errorf("Column %d of line %d value %s exceeds int%d range", cn, line, cv, bits))
+				continue
+			}
+
+			if err != nil {
+				invalid(cn, cv, "type", fmt.Errorf("Column %d of line %d could not be converted to integer. Error: %w", cn, line, err))
+				continue
+			}
+
+			if !sc.inRange(float64(n)) {
+				invalid(cn, cv, "range", fmt.Errorf("column %d line %d value %v outside allowed range [%v,%v]", cn, line, n, sc.printMin(), sc.printMax()))
+				continue
+			}
+
+		case "bool":
+			// Check if the value can be converted to boolean
+			b, err := sch.parseBool(cv)
+			if err != nil {
+				invalid(cn, cv, "type", fmt.Errorf("Column %d of line %d could not be converted to boolean. Error: %w", cn, line, err))
+				continue
+			}
+
+			if sch.Normalize {
+				rec[cn] = strconv.FormatBool(b)
+			}
+
+		case "date":
+			// Check if the value can be converted to date
+			t, err := sc.parseTimeLayout(cc.layouts[cn], cv)
+			if err != nil {
+				invalid(cn, cv, "type", fmt.Errorf("Column %d of line %d could not be converted to date. Error: %w", cn, line, err))
+				continue
+			}
+
+			if !sc.inDateRange(t) {
+				invalid(cn, cv, "range", fmt.Errorf("column %d line %d value %s outside allowed range [%s,%s]", cn, line, cv, sc.MinDate, sc.MaxDate))
+				continue
+			}
+		case "datetime":
+			// Check if the value can be converted to datetime
+			t, err := sc.parseTimeLayout(cc.layouts[cn], cv)
+			if err != nil {
+				invalid(cn, cv, "type", fmt.Errorf("Column %d of line %d could not be converted to datetime. Error: %w", cn, line, err))
+				continue
+			}
+
+			if !sc.inDateRange(t) {
+				invalid(cn, cv, "range", fmt.Errorf("column %d line %d value %s outside allowed range [%s,%s]", cn, line, cv, sc.MinDate, sc.MaxDate))
+				continue
+			}
+		case "time":
+			// Check if the value can be converted to time of day
+			if _, err := sc.parseTimeLayout(cc.layouts[cn], cv); err != nil {
+				invalid(cn, cv, "type", fmt.Errorf("Column %d of line %d could not be converted to time. Error: %w", cn, line, err))
+				continue
+			}
+		case "email":
+			// Check if the value exceeds the length, if there is any
+			if sc.Length > 0 && sch.length(cv) > sc.Length {
+				invalid(cn, cv, "length", fmt.Errorf("Column %d of line %d exceeds specified column length of %d", cn, line, sc.Length))
+				continue
+			}
+
+			// Check if the value looks like an email address
+			if !isEmail(cv) {
+				invalid(cn, cv, "type", fmt.Errorf("Column %d of line %d is not a valid email address", cn, line))
+				continue
+			}
+		case "enum":
+			// Check if the value is one of the choices
+			if !sc.isChoice(cv, sch.CaseInsensitive) {
+				invalid(cn, cv, "type", fmt.Errorf("Column %d of line %d is not one of the allowed values: %s", cn, line, strings.Join(sc.Choices, ", ")))
+				continue
+			}
+		case "uuid":
+			// Check if the value is in the canonical 8-4-4-4-12 format
+			if !isUUID(cv) {
+				invalid(cn, cv, "type", fmt.Errorf("column %d line %d is not a valid UUID", cn, line))
+				continue
+			}
+		case "decimal":
+
+			// Locale separators (1.234,56) and scientific notation are normalized before the digits are counted
+			num := sch.normalizeDecimal(cv)
+			val, ok := num, true
+			if sc.Scientific {
+				val, ok = normalizeScientific(num)
+			}
+
+			// get the whole number and decimal digits. The sign and leading zeros are not counted.
+			whl, dec := "", ""
+			if ok {
+				whl, dec, ok = splitDecimal(val)
+			}
+			if !ok {
+				invalid(cn, cv, "type", fmt.Errorf("Column %d of line %d could not be converted to decimal", cn, line))
+				continue
+			}
+
+			// check if the whole number digits fit in the precision less the scale
+			if len(whl) > sc.Precision-sc.Scale {
+				invalid(cn, cv, "precision", fmt.Errorf("Column %d of line %d exceeds the whole number length as specified by the schema", cn, line))
+				continue
+			}
+
+			// Exact decimals are checked with rational numbers instead of floating point
+			if sch.ExactDecimal {
+				// decimal digits beyond the scale would be lost
+				if len(strings.TrimRight(dec, `0`)) > sc.Scale {
+					invalid(cn, cv, "precision", fmt.Errorf("Column %d of line %d exceeds the decimal scale as specified by the schema", cn, line))
+					continue
+				}
+
+				r, ok := new(big.Rat).SetString(num)
+				if !ok {
+					invalid(cn, cv, "type", fmt.Errorf("Column %d of line %d could not be converted to decimal", cn, line))
+					continue
+				}
+
+				if !sc.inRangeExact(r) {
+					invalid(cn, cv, "range", fmt.Errorf("column %d line %d value %v outside allowed range [%v,%v]", cn, line, cv, sc.printMin(), sc.printMax()))
+					continue
+				}
+				break
+			}
+
+			// Check if the value can be converted to decimal
+			n, err := strconv.ParseFloat(num, 64)
+			if err != nil {
+				invalid(cn, cv, "type", fmt.Errorf("Column %d of line %d could not be converted to decimal. Error: %w", cn, line, err))
+				continue
+			}
+
+			if !sc.inRange(n) {
+				invalid(cn, cv, "range", fmt.Errorf("column %d line %d value %v outside allowed range [%v,%v]", cn, line, cv, sc.printMin(), sc.printMax()))
+				continue
+			}
+		}
+
+		// The custom validator only gets values that passed the checks of the column
+		if sc.Validator != nil && !sc.Validator(cv) {
+			invalid(cn, cv, "validator", fmt.Errorf("Column %d of line %d was rejected by the validator of column %s", cn, line, sc.Name))
+			continue
+		}
+	}
+
+	return
+}
+
+// transform - applies the transforms to the value in order
+func transform(v string, transforms []string) string {
+	for _, t := range transforms {
+		switch t {
+		case "trim":
+			v = strings.TrimSpace(v)
+		case "upper":
+			v = strings.ToUpper(v)
+		case "lower":
+			v = strings.ToLower(v)
+		case "collapsews":
+			v = strings.Join(strings.Fields(v), " ")
+		}
+	}
+	return v
+}
+
+// isUUID - checks if the value is a UUID in the canonical 8-4-4-4-12 hexadecimal format, in any case
+func isUUID(v string) bool {
+	if len(v) != 36 {
+		return false
+	}
+
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// isEmail - basic check of an email address. It requires a single @, a local part and a domain with a dot.
+func isEmail(v string) bool {
+	pos := strings.Index(v, `@`)
+	if pos < 1 || strings.Count(v, `@`) != 1 {
+		return false
+	}
+
+	domain := v[pos+1:]
+	dot := strings.Index(domain, `.`)
+
+	// the domain should not start or end with a dot
+	return dot > 0 && !strings.HasSuffix(domain, `.`) && !strings.ContainsAny(v, " \t")
+}
+
+// isChoice - checks if the value is one of the choices of an enum column
+func (sc *SchemaColumn) isChoice(v string, caseInsensitive bool) bool {
+	for _, c := range sc.Choices {
+		if c == v || caseInsensitive && strings.EqualFold(c, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeDecimal - removes the thousands separator and replaces the decimal separator by a point
+func (sch *Schema) normalizeDecimal(v string) string {
+	if sch.ThousandsSeparator != "" {
+		v = strings.ReplaceAll(v, sch.ThousandsSeparator, "")
+	}
+	if sch.DecimalSeparator != "" && sch.DecimalSeparator != "." {
+		v = strings.ReplaceAll(v, sch.DecimalSeparator, ".")
+	}
+	return v
+}
+
+// parseBool - returns the value of a bool token. The tokens of TrueValues and FalseValues are accepted
+// if there are any. Otherwise, the value is parsed by strconv.ParseBool.
+func (sch *Schema) parseBool(v string) (bool, error) {
+
+	if len(sch.TrueValues) == 0 && len(sch.FalseValues) == 0 {
+		if sch.CaseInsensitive {
+			v = strings.ToLower(v)
+		}
+		return strconv.ParseBool(v)
+	}
+
+	for _, t := range sch.TrueValues {
+		if strings.EqualFold(v, t) {
+			return true, nil
+		}
+	}
+
+	for _, f := range sch.FalseValues {
+		if strings.EqualFold(v, f) {
+			return false, nil
+		}
+	}
+
+	return false, fmt.Errorf("%q is not one of the bool tokens", v)
+}
+
+// splitDecimal - splits a decimal value with an optional sign into its whole number and decimal digits.
+// Leading zeros of the whole number are removed. It returns false if the value is not a valid decimal.
+func splitDecimal(v string) (whl string, dec string, ok bool) {
+	if v != "" && (v[0] == '-' || v[0] == '+') {
+		v = v[1:]
+	}
+
+	whl = v
+	if pos := strings.Index(v, `.`); pos != -1 {
+		whl = v[0:pos]
+		dec = v[pos+1:]
+	}
+
+	if whl == "" && dec == "" {
+		return "", "", false
+	}
+
+	for _, d := range whl + dec {
+		if d < '0' || d > '9' {
+			return "", "", false
+		}
+	}
+
+	return strings.TrimLeft(whl, `0`), dec, true
+}
+
+// normalizeScientific - converts a decimal in scientific notation (-1.2e3) to its plain form (-1200).
+// Values without an exponent are returned as they are.
+func normalizeScientific(v string) (string, bool) {
+	pos := strings.IndexAny(v, `eE`)
+	if pos == -1 {
+		return v, true
+	}
+
+	// the mantissa must be a plain decimal and the exponent an integer
+	if _, _, ok := splitDecimal(v[0:pos]); !ok {
+		return "", false
+	}
+	if _, err := strconv.Atoi(v[pos+1:]); err != nil {
+		return "", false
+	}
+
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return "", false
+	}
+
+	return strconv.FormatFloat(f, 'f', -1, 64), true
+}
+
+// inRangeExact - checks the value against the minimum and maximum of the column without floating point rounding.
+// The bounds are compared in their shortest decimal form, as they are printed in the schema.
+func (sc *SchemaColumn) inRangeExact(v *big.Rat) bool {
+	if sc.Min != nil {
+		if min, ok := new(big.Rat).SetString(sc.printMin()); ok && v.Cmp(min) < 0 {
+			return false
+		}
+	}
+	if sc.Max != nil {
+		if max, ok := new(big.Rat).SetString(sc.printMax()); ok && v.Cmp(max) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// keyTracker - keeps the primary keys of the records seen to find duplicates
+type keyTracker struct {
+	cols  []int          // index of the key columns
+	names []string       // names of the key columns
+	seen  map[string]int // line of the record by key
+}
+
+// newKeyTracker - returns the tracker of the primary key of the schema, or nil if it has none
+func (sch *Schema) newKeyTracker() (*keyTracker, error) {
+	if len(sch.PrimaryKey) == 0 {
+		return nil, nil
+	}
+
+	kt := &keyTracker{
+		cols:  make([]int, len(sch.PrimaryKey)),
+		names: sch.PrimaryKey,
+		seen:  make(map[string]int),
+	}
+
+	for i, name := range sch.PrimaryKey {
+		if kt.cols[i] = sch.columnIndex(name); kt.cols[i] == -1 {
+			return nil, fmt.Errorf("primary key column %q is not in the schema", name)
+		}
+	}
+
+	return kt, nil
+}
+
+// check - returns a validation error if the key of the record was already seen. Values of composite keys
+// are joined with a NUL character, so that ("ab", "c") and ("a", "bc") are different keys.
+func (kt *keyTracker) check(line int, rec []string) *ValidationError {
+	if kt == nil {
+		return nil
+	}
+
+	vals := make([]string, len(kt.cols))
+	for i, cn := range kt.cols {
+		if cn < len(rec) {
+			vals[i] = rec[cn]
+		}
+	}
+	key := strings.Join(vals, "\x00")
+
+	if prev, ok := kt.seen[key]; ok {
+		return &ValidationError{
+			Line:       line,
+			Column:     kt.cols[0],
+			ColumnName: strings.Join(kt.names, "+"),
+			Value:      strings.Join(vals, ","),
+			Kind:       "duplicate",
+			Err:        fmt.Errorf("line %d has the same key (%s) as line %d", line, strings.Join(vals, ","), prev),
+		}
+	}
+	kt.seen[key] = line
+
+	return nil
+}
+
+// columnIndex - index of the column with the name, or -1 if there is none
+func (sch *Schema) columnIndex(name string) int {
+	for cn := range sch.Columns {
+		if sch.Columns[cn].Name == name {
+			return cn
+		}
+	}
+	return -1
+}
@@ -0,0 +1,337 @@
+package webcsv
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// qtySchema - returns a schema of a code and a quantity
+func qtySchema() *Schema {
+	sch := &Schema{Version: "1.0", Delimiter: ","}
+	sch.Columns = []SchemaColumn{
+		{Name: "Code", Type: "string", Length: 3},
+		{Name: "Qty", Type: "int"},
+	}
+	return sch
+}
+
+func TestValidateFailFast(t *testing.T) {
+	data := []byte("abcd,1\nab,x\nab,2\n")
+
+	sch := qtySchema()
+	if _, errs := sch.Validate(data); len(errs) != 2 || errs[0].Line != 1 || errs[1].Line != 2 {
+		t.Fatalf("got %v, want the errors of lines 1 and 2", errs)
+	}
+
+	if err := sch.ValidateStream(bytes.NewReader(data), func([]string) error { return nil }); err == nil ||
+		len(err.(ValidationErrors)) != 2 {
+		t.Fatalf("got %v from the stream, want the errors of lines 1 and 2", err)
+	}
+
+	sch.FailFast = true
+	if _, errs := sch.Validate(data); len(errs) != 1 || errs[0].Line != 1 {
+		t.Fatalf("got %v, want the error of line 1 only", errs)
+	}
+}
+
+func TestValidateThousandsSeparator(t *testing.T) {
+	sch := &Schema{Version: "1.0", Delimiter: ";", ThousandsSeparator: "."}
+	sch.Columns = []SchemaColumn{{Name: "Amount", Type: "decimal", Precision: 10, Scale: 2}}
+
+	// the default decimal point would be read as a thousands separator
+	if _, err := sch.ValidateReturn([]byte("1.5\n")); err == nil {
+		t.Fatal("got no error, want the separators to collide")
+	}
+
+	sch.DecimalSeparator = ","
+	maps, err := sch.ValidateToMaps([]byte("1.234,5\n"))
+	if err != nil || maps[0]["Amount"] != 1234.5 {
+		t.Fatalf("got %v %v, want 1234.5", maps, err)
+	}
+}
+
+func TestQualityReportDuplicates(t *testing.T) {
+	sch := qtySchema()
+	sch.PrimaryKey = []string{"Code", "Qty"}
+
+	report, err := sch.QualityReport([]byte("ab,1\nab,1\nabcd,2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report) != 2 || report["Code"] != 1 || report["Qty"] != 0 {
+		t.Fatalf("got %v, want only the long code counted", report)
+	}
+}
+
+// TestValidateReturnConcurrent - run with go test -race to check the schema is safe for concurrent use
+func TestValidateReturnConcurrent(t *testing.T) {
+	sch := qtySchema()
+	sch.Columns[0].Pattern = `[a-z]+`
+	sch.PrimaryKey = []string{"Code"}
+
+	valid := []byte("ab,1\ncd,2\n")
+	invalid := []byte("ab,1\nab,2\n")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if _, err := sch.ValidateReturn(valid); err != nil {
+				errs <- err
+			}
+			if _, err := sch.ValidateReturn(invalid); err == nil {
+				errs <- errors.New("repeated key passed")
+			}
+			if _, err := sch.Count(valid); err != nil {
+				errs <- err
+			}
+			if !sch.IsValid(sch) || sch.PrintSchema() == "" {
+				errs <- errors.New("schema does not match itself")
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestValidateDecimalDigits(t *testing.T) {
+	sch, err := ParseSchema("ver:1.0; A:decimal(5,2)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for v, ok := range map[string]bool{
+		"123.45":  true,
+		"-123.45": true,
+		"0.5":     true,
+		"12":      true,
+		"00123.4": true,
+		"1.234":   true,
+		"1234.5":  false,
+		"abc":     false,
+	} {
+		if _, err := sch.ValidateReturn([]byte(v + "\n")); (err == nil) != ok {
+			t.Errorf("%s: got %v, want valid %t", v, err, ok)
+		}
+	}
+
+	// exact decimals could not lose the digits beyond the scale
+	sch.ExactDecimal = true
+	if _, err := sch.ValidateReturn([]byte("1.234\n")); err == nil {
+		t.Error("1.234: got no error, want the scale exceeded")
+	}
+	if _, err := sch.ValidateReturn([]byte("1.230\n")); err != nil {
+		t.Errorf("1.230: got %v, want valid", err)
+	}
+}
+
+func TestValidateStrictColumnCount(t *testing.T) {
+	sch := qtySchema()
+	sch.StrictColumnCount = true
+
+	for data, ok := range map[string]bool{
+		"ab,1\n":     true,
+		"ab\n":       false,
+		"ab,1,x\n":   false,
+		"ab,1\nab\n": false,
+	} {
+		if _, err := sch.ValidateReturn([]byte(data)); (err == nil) != ok {
+			t.Errorf("%q: got %v, want valid %t", data, err, ok)
+		}
+	}
+
+	// the fields of the input are counted before they are arranged by the header
+	sch.WithHeader = true
+	sch.MapByHeader = true
+	for data, ok := range map[string]bool{
+		"Qty,Code\n1,ab\n":   true,
+		"Qty,Code\n1\n":      false,
+		"Qty,Code\n1,ab,x\n": false,
+	} {
+		if _, err := sch.ValidateReturn([]byte(data)); (err == nil) != ok {
+			t.Errorf("%q: got %v, want valid %t", data, err, ok)
+		}
+		if _, err := sch.Count([]byte(data)); (err == nil) != ok {
+			t.Errorf("%q: got %v from the stream, want valid %t", data, err, ok)
+		}
+	}
+}
+
+// repeatReader - reads the row n times without keeping the data in memory
+type repeatReader struct {
+	row []byte
+	n   int
+	off int
+}
+
+func (rr *repeatReader) Read(p []byte) (int, error) {
+	if rr.n == 0 {
+		return 0, io.EOF
+	}
+
+	c := copy(p, rr.row[rr.off:])
+	rr.off += c
+	if rr.off == len(rr.row) {
+		rr.off = 0
+		rr.n--
+	}
+	return c, nil
+}
+
+func TestValidateStreamMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("reads 32 MB of data")
+	}
+
+	sch := qtySchema()
+	sch.Columns[0].Length = 20
+
+	// the data is much larger than the heap allowed during the validation
+	const limit = 16 << 20
+	row := []byte("abcdefghijklmnop,12345\n")
+	rd := &repeatReader{row: row, n: 32 << 20 / len(row)}
+
+	var ms runtime.MemStats
+	peak, n := uint64(0), 0
+	err := sch.ValidateStream(rd, func([]string) error {
+		if n++; n%100000 == 0 {
+			runtime.ReadMemStats(&ms)
+			if ms.HeapAlloc > peak {
+				peak = ms.HeapAlloc
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 32<<20/len(row) || peak > limit {
+		t.Fatalf("got %d records with a heap of %d bytes, want %d records within %d bytes", n, peak, 32<<20/len(row), limit)
+	}
+}
+
+func TestValidateScientific(t *testing.T) {
+	for _, tt := range []struct {
+		raw  string
+		data string
+		ok   bool
+	}{
+		{"ver:1.0; A:decimal(5,1)", "-12.5", true},
+		{"ver:1.0; A:decimal(5,1)", "+3", true},
+		{"ver:1.0; A:decimal(5,1)", "1.2e3", false},
+		{"ver:1.0; A:decimal(5,1)e", "1.2e3", true},
+		{"ver:1.0; A:decimal(5,1)e", "-12.5", true},
+	} {
+		sch, err := ParseSchema(tt.raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err = sch.ValidateReturn([]byte(tt.data + "\n")); (err == nil) != tt.ok {
+			t.Errorf("%s with %s: got %v, want valid %t", tt.data, tt.raw, err, tt.ok)
+		}
+	}
+
+	// only decimals could be in scientific notation
+	if _, err := ParseSchema("ver:1.0; A:int()e"); err == nil {
+		t.Error("got no error, want the scientific int rejected")
+	}
+}
+
+func TestValidateExactDecimal(t *testing.T) {
+	for _, tt := range []struct {
+		raw  string
+		data string
+		ok   bool
+	}{
+		// the values differ from the bound only in a digit that floating point rounds away
+		{"ver:1.0; A:decimal(20,17)[..0.3]", "0.30000000000000001", false},
+		{"ver:1.0; A:decimal(20,17)[..0.3]", "0.29999999999999999", true},
+		{"ver:1.0; A:decimal(20,17)[0.3..]", "0.29999999999999999", false},
+		{"ver:1.0; A:decimal(20,0)[..9007199254740992]", "9007199254740993", false},
+		{"ver:1.0; A:decimal(20,0)[..9007199254740992]", "9007199254740992", true},
+	} {
+		sch, err := ParseSchema(tt.raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// floating point could not tell the value from the bound
+		if _, err = sch.ValidateReturn([]byte(tt.data + "\n")); err != nil {
+			t.Errorf("%s with %s: got %v, want valid without exact decimals", tt.data, tt.raw, err)
+		}
+
+		sch.ExactDecimal = true
+		if _, err = sch.ValidateReturn([]byte(tt.data + "\n")); (err == nil) != tt.ok {
+			t.Errorf("%s with %s: got %v, want valid %t", tt.data, tt.raw, err, tt.ok)
+		}
+	}
+}
+
+func TestValidateStreamCancel(t *testing.T) {
+	sch := qtySchema()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the validation is cancelled partway through the input
+	const rows = 100 * contextCheckRows
+	n := 0
+	err := sch.ValidateStreamContext(ctx, &repeatReader{row: []byte("ab,1\n"), n: rows}, func([]string) error {
+		if n++; n == rows/2 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+
+	if n < rows/2 || n > rows/2+contextCheckRows {
+		t.Fatalf("got %d records, want the validation stopped within %d records of the cancel", n, contextCheckRows)
+	}
+}
+
+func TestValidateTrailingRow(t *testing.T) {
+	sch := qtySchema()
+	sch.Columns[1].Nullable = true
+
+	// a last record of empty fields is data, a blank line is not
+	for data, want := range map[string]int{
+		"ab,1\n,\n":  2,
+		"ab,1\n,":    2,
+		"ab,1\n \n":  1,
+		"ab,1\n\t":   1,
+		"ab,1\n\n\n": 1,
+	} {
+		recs, err := sch.ValidateReturn([]byte(data))
+		if err != nil || len(recs) != want {
+			t.Errorf("%q: got %q %v, want %d records", data, recs, err, want)
+		}
+	}
+
+	// the empty fields are validated like any other
+	sch.Columns[1].Nullable = false
+	_, errs := sch.Validate([]byte("ab,1\n,\n"))
+	if len(errs) != 1 || errs[0].Line != 2 || errs[0].Column != 1 {
+		t.Fatalf("got %v, want the empty quantity of line 2 rejected", errs)
+	}
+
+	if _, err := sch.Count([]byte("ab,1\n,\n")); err == nil {
+		t.Fatal("got no error from the stream, want the empty quantity rejected")
+	}
+}
@@ -1,368 +1,1751 @@
 package webcsv
 
 import (
-	"bytes"
-	"encoding/csv"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 )
 
 // SchemaColumn - schema column
 type SchemaColumn struct {
-	Name      string
-	Type      string
-	Length    int
-	Precision int
-	Scale     int
+	Name       string
+	Type       string
+	Length     int
+	Precision  int
+	Scale      int
+	Nullable   bool     // empty values are accepted without type checking
+	Format     string   // optional layout of date, datetime and time values
+	Choices    []string // allowed values of enum columns
+	Pattern    string   // optional regular expression the whole value must match
+	Default    string   // optional value used in place of empty values
+	Scientific bool     // decimal values may be in scientific notation (1.2e3)
+	Unit       string   // optional suffix int and decimal values must end with, such as % or kg
+	Required   bool     // the column must be present in the input, even if it is nullable
+	Min        *float64 // optional minimum of int and decimal values
+	Max        *float64 // optional maximum of int and decimal values
+	MinDate    string   // optional earliest date and datetime value in the column layout, or now
+	MaxDate    string   // optional latest date and datetime value in the column layout, or now
+	Transforms []string // optional transforms applied in order to the value before it is validated
+	Start      int      // offset in characters of the value in fixed mode, or zero to follow the previous column
+	Index      *int     // optional position of the value in the input records, starting from 0
+	Bits       int      // optional bit width of int values (int16): 8, 16, 32 or 64. Zero is 64.
+
+	// Validator optionally checks the value after the checks of the column type, such as a lookup of
+	// the value in an external set. It can only be set after parsing, since it is not part of the schema
+	// string. Validator is not compared by Equal and Diff.
+	Validator func(string) bool `json:"-"`
 }
 
+// layout - time layout used to parse and format date, datetime and time values
+func (sc *SchemaColumn) layout() string {
+	if sc.Format != "" {
+		return sc.Format
+	}
+
+	switch sc.Type {
+	case "date":
+		return "2006-01-02"
+	case "time":
+		return "15:04:05"
+	}
+	return time.RFC3339
+}
+
+// parseTime - parses a date, datetime or time value. Time values may omit the seconds.
+func (sc *SchemaColumn) parseTime(cv string) (time.Time, error) {
+	return sc.parseTimeLayout(sc.layout(), cv)
+}
+
+// dateBound - returns the time of a date range bound. The bound now is the current time. An empty bound is nil.
+func (sc *SchemaColumn) dateBound(b string) (*time.Time, error) {
+	if b == "" {
+		return nil, nil
+	}
+
+	if b == "now" {
+		t := time.Now()
+		return &t, nil
+	}
+
+	t, err := sc.parseTime(b)
+	if err != nil {
+		return nil, fmt.Errorf("column %q has an invalid date range bound %q", sc.Name, b)
+	}
+
+	return &t, nil
+}
+
+// inDateRange - checks if the time is within the date range of the column
+func (sc *SchemaColumn) inDateRange(t time.Time) bool {
+	if min, err := sc.dateBound(sc.MinDate); err == nil && min != nil && t.Before(*min) {
+		return false
+	}
+
+	if max, err := sc.dateBound(sc.MaxDate); err == nil && max != nil && t.After(*max) {
+		return false
+	}
+
+	return true
+}
+
+// parseTimeLayout - parses a date, datetime or time value with the layout of the column
+func (sc *SchemaColumn) parseTimeLayout(layout string, cv string) (time.Time, error) {
+	t, err := time.Parse(layout, cv)
+	if err != nil && sc.Type == "time" && sc.Format == "" {
+		if st, serr := time.Parse("15:04", cv); serr == nil {
+			return st, nil
+		}
+	}
+	return t, err
+}
+
+// inRange - checks the value against the minimum and maximum of the column
+func (sc *SchemaColumn) inRange(v float64) bool {
+	if sc.Min != nil && v < *sc.Min {
+		return false
+	}
+	if sc.Max != nil && v > *sc.Max {
+		return false
+	}
+	return true
+}
+
+// printMin - minimum as printed in the schema. An open minimum is an empty string.
+func (sc *SchemaColumn) printMin() string {
+	return printBound(sc.Min)
+}
+
+// printMax - maximum as printed in the schema. An open maximum is an empty string.
+func (sc *SchemaColumn) printMax() string {
+	return printBound(sc.Max)
+}
+
+func printBound(b *float64) string {
+	if b == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*b, 'f', -1, 64)
+}
+
+func equalBound(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// equalIndex - checks if both indexes are not set or have the same value
+func equalIndex(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// parseRange - parses a min..max range. Either of the bounds can be omitted.
+func parseRange(rng string) (min *float64, max *float64, err error) {
+	pos := strings.Index(rng, `..`)
+	if pos == -1 {
+		return nil, nil, fmt.Errorf("invalid range %q", rng)
+	}
+
+	bound := func(v string) (*float64, error) {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			return nil, nil
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid range bound %q", v)
+		}
+		return &f, nil
+	}
+
+	if min, err = bound(rng[0:pos]); err != nil {
+		return
+	}
+	if max, err = bound(rng[pos+2:]); err != nil {
+		return
+	}
+
+	if min != nil && max != nil && *min > *max {
+		err = fmt.Errorf("invalid range %q, minimum is greater than maximum", rng)
+	}
+
+	return
+}
+
+// extractPatterns - takes out the patterns enclosed in slashes outside of parenthesis and brackets, like
+// the date formats and ranges, and replaces them by their index enclosed in NUL characters. A slash in a
+// pattern is escaped by a backslash.
+func extractPatterns(schp string) (string, []string, error) {
+
+	var (
+		out      strings.Builder
+		patterns []string
+	)
+
+	depth := 0
+	for i := 0; i < len(schp); i++ {
+		c := schp[i]
+
+		switch {
+		case c == '(' || c == '[':
+			depth++
+		case (c == ')' || c == ']') && depth > 0:
+			depth--
+		case c == '/' && depth == 0:
+			// find the closing slash
+			end := -1
+			for j := i + 1; j < len(schp); j++ {
+				if schp[j] == '\\' {
+					j++
+					continue
+				}
+				if schp[j] == '/' {
+					end = j
+					break
+				}
+			}
+
+			if end == -1 {
+				return "", nil, fmt.Errorf("unterminated pattern %s", schp[i:])
+			}
+
+			out.WriteString("\x00" + strconv.Itoa(len(patterns)) + "\x00")
+			patterns = append(patterns, unescapeSlashes(schp[i+1:end]))
+			i = end
+			continue
+		}
+
+		out.WriteByte(c)
+	}
+
+	return out.String(), patterns, nil
+}
+
+// unescapeSlashes - removes the backslash of the escaped slashes of a pattern. Other escapes are kept
+// for the regular expression.
+func unescapeSlashes(p string) string {
+	var out strings.Builder
+	for i := 0; i < len(p); i++ {
+		if p[i] == '\\' && i+1 < len(p) {
+			if p[i+1] != '/' {
+				out.WriteByte(p[i])
+			}
+			i++
+			out.WriteByte(p[i])
+			continue
+		}
+		out.WriteByte(p[i])
+	}
+	return out.String()
+}
+
+// escapeSlashes - escapes the slashes of a pattern that are not escaped yet, so it can be printed in slashes
+func escapeSlashes(p string) string {
+	var out strings.Builder
+	for i := 0; i < len(p); i++ {
+		switch {
+		case p[i] == '\\' && i+1 < len(p):
+			out.WriteString(p[i : i+2])
+			i++
+		case p[i] == '/':
+			out.WriteString(`\/`)
+		default:
+			out.WriteByte(p[i])
+		}
+	}
+	return out.String()
+}
+
+// splitUnquoted - splits v by sep outside of double quotes into at most n parts. A negative n returns all parts.
+func splitUnquoted(v string, sep byte, n int) []string {
+	parts := make([]string, 0)
+
+	quoted := false
+	start := 0
+	for i := 0; i < len(v) && n != len(parts)+1; i++ {
+		switch {
+		case v[i] == '\\' && quoted:
+			i++ // skip the escaped character
+		case v[i] == '"':
+			quoted = !quoted
+		case v[i] == sep && !quoted:
+			parts = append(parts, v[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, v[start:])
+}
+
+// propertyValue - decodes a quoted ("\t") or percent encoded (%2C) property value. Percent signs that do
+// not start a valid sequence (del:%) are kept as they are.
+func propertyValue(v string) (string, error) {
+	t := strings.TrimSpace(v)
+
+	if strings.HasPrefix(t, `"`) {
+		return strconv.Unquote(t)
+	}
+
+	if strings.Contains(t, `%`) {
+		return unescapePercent(t), nil
+	}
+
+	return v, nil
+}
+
+// defaultSpecial - characters of a default value that would be read as schema syntax
+const defaultSpecial = "%,;|/()[]"
+
+// escapeDefault - percent encodes the characters of a default value that could not be parsed back as they
+// are, including the spaces at its ends
+func escapeDefault(v string) string {
+	var out strings.Builder
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		if strings.IndexByte(defaultSpecial, c) != -1 || c == ' ' && (i == 0 || i == len(v)-1) {
+			fmt.Fprintf(&out, "%%%02X", c)
+			continue
+		}
+		out.WriteByte(c)
+	}
+	return out.String()
+}
+
+// unescapePercent - decodes the valid percent encoded sequences (%2C) of v. Other percent signs are kept.
+func unescapePercent(v string) string {
+	if !strings.Contains(v, "%") {
+		return v
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(v); i++ {
+		if v[i] == '%' && i+2 < len(v) {
+			if b, err := strconv.ParseUint(v[i+1:i+3], 16, 8); err == nil {
+				out.WriteByte(byte(b))
+				i += 2
+				continue
+			}
+		}
+		out.WriteByte(v[i])
+	}
+	return out.String()
+}
+
+// printDelimiter - quotes a delimiter that could not be parsed back as it is
+func printDelimiter(d string) string {
+	if d == "," {
+		return d
+	}
+
+	if strings.ContainsAny(d, ",;:\"% ") || strconv.Quote(d) != `"`+d+`"` {
+		return strconv.Quote(d)
+	}
+
+	return d
+}
+
+// indexOutside - index of the first instance of c outside of parenthesis, or -1 if there is none
+func indexOutside(v string, c byte) int {
+	depth := 0
+	for i := 0; i < len(v); i++ {
+		switch v[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+			}
+		case c:
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// compilePattern - compiles a pattern that has to match the whole value
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern /%s/: %s", pattern, err.Error())
+	}
+	return re, nil
+}
+
+// atoiParam - converts a length, precision or scale parameter. An empty parameter (int()) is zero.
+func atoiParam(p string) (int, error) {
+	p = strings.TrimSpace(p)
+	if p == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(p)
+	if err != nil {
+		return 0, fmt.Errorf("invalid parameter %q", p)
+	}
+
+	return n, nil
+}
+
+// isShorthand - checks if the column is a string(4000) column that could be written as its name only
+func (sc *SchemaColumn) isShorthand() bool {
+	return sc.Type == "string" && sc.Length == 4000 &&
+		sc.Name != "" && !strings.ContainsAny(sc.Name, ":,;") &&
+		!sc.Nullable && !sc.Required &&
+		sc.Pattern == "" && sc.Default == "" && sc.Format == "" && sc.Unit == "" && len(sc.Transforms) == 0 &&
+		sc.Min == nil && sc.Max == nil
+}
+
+// check - checks the column definition for consistency
+func (sc *SchemaColumn) check() error {
+	if !knownTypes[sc.Type] {
+		return fmt.Errorf("unknown column type %q for column %q", sc.Type, sc.Name)
+	}
+
+	if sc.Length < 0 {
+		return fmt.Errorf("column %q has a negative length of %d", sc.Name, sc.Length)
+	}
+
+	if sc.Index != nil && *sc.Index < 0 {
+		return fmt.Errorf("column %q has a negative index of %d", sc.Name, *sc.Index)
+	}
+
+	if sc.Precision < 0 || sc.Scale < 0 {
+		return fmt.Errorf("column %q has a negative precision or scale", sc.Name)
+	}
+
+	if sc.Scale > sc.Precision {
+		return fmt.Errorf("column %q has a scale of %d greater than its precision of %d", sc.Name, sc.Scale, sc.Precision)
+	}
+
+	if sc.Bits != 0 && (sc.Type != "int" || sc.Bits != 8 && sc.Bits != 16 && sc.Bits != 32 && sc.Bits != 64) {
+		return fmt.Errorf("column %q of type %s can not have %d bits", sc.Name, sc.Type, sc.Bits)
+	}
+
+	if sc.Scientific && sc.Type != "decimal" {
+		return fmt.Errorf("column %q of type %s can not be in scientific notation", sc.Name, sc.Type)
+	}
+
+	if sc.Unit != "" && sc.Type != "int" && sc.Type != "decimal" {
+		return fmt.Errorf("column %q of type %s can not have a unit", sc.Name, sc.Type)
+	}
+
+	for _, t := range sc.Transforms {
+		if !knownTransforms[t] {
+			return fmt.Errorf("unknown transform %q for column %q", t, sc.Name)
+		}
+	}
+
+	if sc.MinDate != "" || sc.MaxDate != "" {
+		if sc.Type != "date" && sc.Type != "datetime" {
+			return fmt.Errorf("column %q of type %s can not have a date range", sc.Name, sc.Type)
+		}
+
+		min, err := sc.dateBound(sc.MinDate)
+		if err != nil {
+			return err
+		}
+
+		max, err := sc.dateBound(sc.MaxDate)
+		if err != nil {
+			return err
+		}
+
+		if min != nil && max != nil && sc.MinDate != "now" && sc.MaxDate != "now" && min.After(*max) {
+			return fmt.Errorf("column %q has a minimum date after its maximum date", sc.Name)
+		}
+	}
+
+	return nil
+}
+
+// knownTypes - column types that can be validated
+var knownTypes = map[string]bool{
+	"string":   true,
+	"int":      true,
+	"bool":     true,
+	"date":     true,
+	"datetime": true,
+	"time":     true,
+	"decimal":  true,
+	"uuid":     true,
+	"email":    true,
+	"enum":     true,
+}
+
+// intBits - bit width of the sized integer types, which are parsed as int columns
+var intBits = map[string]int{
+	"int8":  8,
+	"int16": 16,
+	"int32": 32,
+	"int64": 64,
+}
+
+// knownTransforms - transforms that can be applied to values before they are validated
+var knownTransforms = map[string]bool{
+	"trim":       true, // removes leading and trailing spaces
+	"upper":      true, // converts to upper case
+	"lower":      true, // converts to lower case
+	"collapsews": true, // replaces runs of spaces by a single space and trims the value
+}
+
+// Bool tokens of legacy exports that can be set as TrueValues and FalseValues of a schema
+var (
+	TrueTokens  = []string{"yes", "y", "1", "t", "true"}
+	FalseTokens = []string{"no", "n", "0", "f", "false"}
+)
+
+// Version policies
+const (
+	VersionExact           = "exact"           // major, minor and patch must be equal. 1.0 and 1.0.0 are equal.
+	VersionMajor           = "major"           // major must be equal
+	VersionMinorCompatible = "minorCompatible" // major must be equal and the other minor must not be newer
+)
+
 // Schema - schema
+//
+// A schema is safe for concurrent use by multiple goroutines. Validate, ValidateReturn, ValidateStream,
+// IsValid and PrintSchema only read the schema, and the compiled column data they share is guarded
+// internally. The fields should not be modified while the schema is in use.
 type Schema struct {
 	Version    string
 	WithHeader bool
 	Delimiter  string
 	Columns    []SchemaColumn
-	isloaded   bool
+
+	// MapByHeader matches the record values to the columns by the header names instead of their position
+	// when WithHeader is true. The records returned by validation are arranged in the order of the columns.
+	MapByHeader bool
+
+	// VerifyHeader checks that the header names match the column names when WithHeader is true
+	VerifyHeader bool
+
+	// HeaderCaseSensitive matches the header names to the column names with the same case
+	HeaderCaseSensitive bool
+
+	// TrimFields removes leading and trailing spaces of values before they are validated.
+	// The records returned by validation have the trimmed values.
+	TrimFields bool
+
+	// ApplyDefaults replaces empty values by the column default in the records returned by validation.
+	// DecodeInto always uses the default of empty values.
+	ApplyDefaults bool
+
+	// OmitHeader excludes the header from the records returned by ValidateReturn
+	OmitHeader bool
+
+	// VersionPolicy sets how IsValid compares versions: exact, major (the default) or minorCompatible
+	VersionPolicy string
+
+	// CaseInsensitive makes enum and bool values match regardless of case. The records keep the original values.
+	CaseInsensitive bool
+
+	// FailFast stops the validation at the first line with errors. Otherwise, all lines are validated
+	// and every error found is returned.
+	FailFast bool
+
+	// TrueValues and FalseValues are the tokens accepted for bool values, such as Y and N, compared
+	// case-insensitively. When both are empty, bool values are parsed by strconv.ParseBool.
+	// TrueTokens and FalseTokens are a common set of tokens.
+	TrueValues  []string
+	FalseValues []string
+
+	// StripUnits removes the unit of int and decimal values from the returned records
+	StripUnits bool
+
+	// Normalize rewrites the bool values of the returned records to true or false
+	Normalize bool
+
+	// DecimalSeparator and ThousandsSeparator are the separators of decimal values, as in 1.234,56.
+	// When they are empty, decimals have a point as decimal separator and no thousands separator.
+	DecimalSeparator   string
+	ThousandsSeparator string
+
+	// ExactDecimal validates decimal values with exact rational numbers instead of floating point.
+	// Decimal digits beyond the scale are rejected instead of being rounded.
+	ExactDecimal bool
+
+	// ByteLength counts the length of string values in bytes instead of characters
+	ByteLength bool
+
+	// CommentChar starts a comment line that is skipped during validation. Zero has no comment lines.
+	// Comment lines are not returned, but line numbers of validation errors still refer to the
+	// physical lines of the data.
+	CommentChar rune
+
+	// LazyQuotes allows quotes in unquoted fields and unescaped quotes in quoted fields, as in csv.Reader
+	LazyQuotes bool
+
+	// Mode is delimited (the default) or fixed (mode:fixed). In fixed mode, the values are cut from each line
+	// by the Start and the width of the columns instead of being separated by the delimiter, and their padding
+	// is removed. The width is the Length, or for columns without a length, the widest value of their type
+	// (see fixedWidth).
+	Mode string
+
+	// BackslashEscape reads a backslash as the escape of the next character (Doe\, Jr.) in place of CSV
+	// quoting. The delimiter, a line break or a backslash could be escaped. Double quotes are not special.
+	BackslashEscape bool
+
+	// FieldsPerRecord is the number of fields each record must have, as in csv.Reader. Zero takes the number
+	// of fields of the first record, and a negative number allows records with different number of fields.
+	// It is ignored when StrictColumnCount is set.
+	FieldsPerRecord int
+
+	// StrictTrailingRow validates a last record of a single whitespace field like any other record. Otherwise,
+	// it is skipped, since it is usually left by a blank line at the end of the data.
+	StrictTrailingRow bool
+
+	// StrictColumnCount rejects records whose number of fields differ from the number of columns
+	StrictColumnCount bool
+
+	// PrimaryKey names the columns whose values identify a record. Records with the same values of the
+	// key columns are reported as duplicates.
+	PrimaryKey []string
+
+	// MaxRows and MaxBytes stop the validation with a LimitError once the data records or the bytes of the
+	// input exceed them. Zero has no limit. The bytes of compressed input are counted after decompression.
+	MaxRows  int
+	MaxBytes int64
+
+	// Charset is the encoding of the input (cs:latin1, cs:latin9 or cs:cp1252). The input is transcoded to
+	// UTF-8 before it is parsed, so the lengths are counted on the decoded characters. Empty or utf-8 reads
+	// the input as it is.
+	Charset string
+
+	// Migrator converts the data of older schema versions to this version in Migrate. It is shared by
+	// clones and is not compared by Equal.
+	Migrator *Migrator `json:"-"`
+
+	isloaded bool
+
+	mu    sync.Mutex   // guards the cache
+	cache *columnCache // compiled column data
+}
+
+// ParseSchema - parse WebCSV schema. This is a basic function. We can improve it later.
+func ParseSchema(raw string) (schema *Schema, Error error) {
+	schema, _, Error = parseSchema(raw, false)
+	return
+}
+
+// ParseSchemaStrict - parse WebCSV schema like ParseSchema, and also return warnings about constructs that
+// are accepted but may not be what the author meant, like the default type of a column with only a name.
+// Strict callers could treat the warnings as errors. Unknown properties are errors, since a misspelled
+// property (delim:|) would leave the delimiter at its default.
+func ParseSchemaStrict(raw string) (schema *Schema, Warnings []string, Error error) {
+	return parseSchema(raw, true)
+}
+
+// parseSchema - parse WebCSV schema and collect the warnings. Unknown properties are errors when strict.
+func parseSchema(raw string, strict bool) (schema *Schema, Warnings []string, Error error) {
+	schema = &Schema{
+		isloaded: false,
+	}
+
+	// split the header
+	parts := splitUnquoted(raw, ';', 2) // enum choices in the column section are separated by semicolons
+
+	// A schema without the column section can not be used to validate anything
+	if len(parts) < 2 {
+		Error = errors.New(`schema missing column definition section`)
+		return
+	}
+
+	// First part: schema properties. These are separated by comma
+	prop := splitUnquoted(parts[0], ',', -1)
+	seen := make(map[string]bool, len(prop))
+	for _, v := range prop {
+
+		// A trailing comma (as in del:,) leaves an empty property
+		if strings.TrimSpace(v) == "" {
+			continue
+		}
+
+		kv := strings.SplitN(v, `:`, 2)
+		if len(kv) != 2 {
+			Error = fmt.Errorf(`invalid schema property %q`, v)
+			return
+		}
+
+		// Values could be quoted ("\t") or percent encoded (%2C)
+		kv[1], Error = propertyValue(kv[1])
+		if Error != nil {
+			Error = fmt.Errorf(`invalid schema property %q: %s`, v, Error.Error())
+			return
+		}
+
+		key := strings.TrimSpace(kv[0])
+		if seen[key] {
+			Warnings = append(Warnings, fmt.Sprintf("property %q is set more than once, the last value is used", key))
+		}
+		seen[key] = true
+
+		// Spaces around the values are not part of them, except for a delimiter that is only a space or tab
+		switch key {
+		case "ver":
+			schema.Version = strings.TrimSpace(kv[1])
+		case "hdr":
+			var err error
+			schema.WithHeader, err = strconv.ParseBool(strings.TrimSpace(kv[1]))
+			if err != nil {
+				Warnings = append(Warnings, fmt.Sprintf("invalid hdr value %q, the header is disabled", strings.TrimSpace(kv[1])))
+			}
+		case "cs":
+			schema.Charset = strings.TrimSpace(kv[1])
+		case "mode":
+			schema.Mode = strings.ToLower(strings.TrimSpace(kv[1]))
+		case "del":
+			schema.Delimiter = kv[1]
+			if t := strings.TrimSpace(kv[1]); t != "" {
+				schema.Delimiter = t
+			}
+			if schema.Delimiter == "" {
+				schema.Delimiter = ","
+			}
+		default:
+			// unknown properties are ignored by ParseSchema so that newer clients could send more of them
+			if strict {
+				Error = fmt.Errorf("unknown property %q%s", key, suggestProperty(key))
+				return
+			}
+		}
+	}
+
+	// The input could not be read in an unknown charset
+	if _, Error = schema.charset(); Error != nil {
+		return
+	}
+
+	if !seen["ver"] {
+		Warnings = append(Warnings, "schema has no version")
+	}
+
+	// Second part: schema columns.
+	// Patterns are taken out first since they may contain commas, colons and brackets
+	schp, patterns, err := extractPatterns(parts[1])
+	if err != nil {
+		Error = err
+		return
+	}
+
+	// Commas inside parentheses separate the parameters of a column (decimal(13,3)), not the columns.
+	// They are replaced by semicolons since semicolons were already parsed. The depth of the parentheses
+	// is tracked so that every group in every column is handled the same.
+	schpart := []rune(schp)
+	depth := 0
+	for i, c := range schpart {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				Error = fmt.Errorf("unbalanced parenthesis at position %d of the column section", i)
+				return
+			}
+			depth--
+		case ',':
+			if depth > 0 {
+				schpart[i] = ';'
+			}
+		}
+	}
+
+	// An unclosed group would take the columns after it as its parameters
+	if depth != 0 {
+		Error = errors.New("unclosed parenthesis in the column section")
+		return
+	}
+
+	sch := strings.Split(string(schpart), `,`)
+	schema.Columns = make([]SchemaColumn, len(sch))
+
+	if len(sch) == 0 {
+		Error = errors.New(`No schema defined`)
+		return
+	}
+
+	loadedcols := false
+
+	for i, v := range sch {
+
+		// get name and value
+		nv := strings.SplitN(v, `:`, 2) // a format like 15:04:05 may contain colons
+
+		name := strings.TrimSpace(nv[0])
+
+		// A column with one element will be treated as:
+		// - Column name
+		// - string as default type
+		// - maximum length of 4000
+		if len(nv) == 1 {
+			schema.Columns[i].Name = name
+			schema.Columns[i].Type = "string"
+			schema.Columns[i].Length = 4000
+			Warnings = append(Warnings, fmt.Sprintf("column %s: type defaulted to string(4000)", name))
+
+			loadedcols = true
+			continue
+		}
+
+		// A column with two elements
+		if len(nv) == 2 {
+			schema.Columns[i].Length = 0
+			schema.Columns[i].Precision = 0
+			schema.Columns[i].Scale = 0
+			schema.Columns[i].Name = name
+
+			// extract length if there is any
+			col := strings.TrimSpace(nv[1])
+
+			// Transforms at the end after bars (string(20)|trim|upper) are applied before the validation
+			if pos := indexOutside(col, '|'); pos != -1 {
+				for _, t := range strings.Split(col[pos+1:], `|`) {
+					schema.Columns[i].Transforms = append(schema.Columns[i].Transforms, strings.ToLower(strings.TrimSpace(t)))
+				}
+				col = strings.TrimSpace(col[0:pos])
+			}
+
+			// A value after an equal sign outside of parenthesis (int=0) is the default of empty values.
+			// Characters of the schema syntax in it are percent encoded (string(5)=a%2Cb).
+			if pos := indexOutside(col, '='); pos != -1 {
+				schema.Columns[i].Default = unescapePercent(strings.TrimSpace(col[pos+1:]))
+				col = strings.TrimSpace(col[0:pos])
+			}
+
+			// A number after an at sign (string(3)@10) is the start of the value in fixed mode
+			if pos := indexOutside(col, '@'); pos != -1 {
+				if schema.Columns[i].Start, Error = atoiParam(col[pos+1:]); Error != nil {
+					Error = fmt.Errorf("Column %s has an invalid start: %s", name, Error.Error())
+					return
+				}
+				col = strings.TrimSpace(col[0:pos])
+			}
+
+			// A pattern in slashes (string(20)/[A-Z]{3}-\d{4}/) was replaced by its index between NUL characters
+			if pos := strings.Index(col, "\x00"); pos != -1 {
+				end := pos + 1 + strings.Index(col[pos+1:], "\x00")
+				n, _ := strconv.Atoi(col[pos+1 : end])
+				schema.Columns[i].Pattern = patterns[n]
+				if _, Error = compilePattern(patterns[n]); Error != nil {
+					Error = fmt.Errorf("Column %s: %s", name, Error.Error())
+					return
+				}
+				col = strings.TrimSpace(col[0:pos] + col[end+1:])
+			}
+
+			// An exclamation mark at the end (int!, int?!) makes the column required in the input
+			if strings.HasSuffix(col, `!`) {
+				schema.Columns[i].Required = true
+				col = strings.TrimSpace(strings.TrimSuffix(col, `!`))
+			}
+
+			// A question mark after the type (int?, decimal(13,3)?) makes the column nullable
+			if strings.HasSuffix(col, `?`) {
+				schema.Columns[i].Nullable = true
+				col = strings.TrimSpace(strings.TrimSuffix(col, `?`))
+			}
+
+			// A range in brackets (int[0..150]) sets the minimum and maximum values. The range of dates
+			// (date[1900-01-01..now]) is kept as it is written, since it is parsed with the column layout.
+			if pos := strings.Index(col, `[`); pos != -1 && strings.HasSuffix(col, `]`) {
+				rng := col[pos+1 : len(col)-1]
+				switch strings.ToLower(strings.TrimSpace(strings.SplitN(col[0:pos], `(`, 2)[0])) {
+				case "date", "datetime":
+					dpos := strings.Index(rng, `..`)
+					if dpos == -1 {
+						Error = fmt.Errorf("Column %s: invalid range %q", name, rng)
+						return
+					}
+					schema.Columns[i].MinDate = strings.TrimSpace(rng[0:dpos])
+					schema.Columns[i].MaxDate = strings.TrimSpace(rng[dpos+2:])
+				default:
+					schema.Columns[i].Min, schema.Columns[i].Max, Error = parseRange(rng)
+					if Error != nil {
+						Error = fmt.Errorf("Column %s: %s", name, Error.Error())
+						return
+					}
+				}
+				col = strings.TrimSpace(col[0:pos])
+			}
+
+			// An e after the parameters (decimal(13,3)e) accepts scientific notation. Any other text after
+			// the parameters (decimal(5,2)%, int()kg) is the unit. A unit is quoted after an e (decimal(13,3)e"kg").
+			if pos := strings.LastIndex(col, `)`); pos != -1 && pos < len(col)-1 {
+				unit := strings.TrimSpace(col[pos+1:])
+				if unit == `e` || strings.HasPrefix(unit, `e"`) {
+					schema.Columns[i].Scientific = true
+					unit = unit[1:]
+				}
+
+				if len(unit) > 1 && strings.HasPrefix(unit, `"`) && strings.HasSuffix(unit, `"`) {
+					unit = unit[1 : len(unit)-1]
+				}
+
+				schema.Columns[i].Unit = unit
+				col = col[0 : pos+1]
+			}
+
+			schema.Columns[i].Type = strings.ToLower(col)
+
+			// the opening parenthesis '(' separates the type name from its parameters
+			pos := strings.Index(col, `(`)
+			lps := ""
+			if pos != -1 {
+				schema.Columns[i].Type = strings.ToLower(strings.TrimSpace(col[0:pos])) // type name
+
+				lps = strings.TrimSpace(strings.TrimSuffix(col[pos+1:], `)`)) // get length, precision and scale or format
+
+				switch schema.Columns[i].Type {
+				case "date", "datetime", "time":
+					// The parameter of time types is the layout. Commas were replaced by semicolons earlier.
+					schema.Columns[i].Format = strings.ReplaceAll(lps, `;`, `,`)
+				case "enum":
+					// The allowed values are separated by semicolons
+					for _, c := range strings.Split(lps, `;`) {
+						schema.Columns[i].Choices = append(schema.Columns[i].Choices, strings.TrimSpace(c))
+					}
+				default:
+					// check if the type has comma. A comma represents the precision and scale.
+					// If there is no comma (semicolon replaced it earlier), it is just the length
+					pos = strings.Index(lps, `;`)
+					if pos != -1 {
+						schema.Columns[i].Precision, Error = atoiParam(lps[0:pos])
+						if Error == nil {
+							schema.Columns[i].Scale, Error = atoiParam(lps[pos+1:])
+						}
+					} else if schema.Columns[i].Type == "decimal" {
+						// a decimal with one parameter has no decimal digits, as decimal(5) in SQL
+						schema.Columns[i].Precision, Error = atoiParam(lps)
+					} else {
+						schema.Columns[i].Length, Error = atoiParam(lps)
+					}
+
+					if Error != nil {
+						Error = fmt.Errorf("Column %s: %s", name, Error.Error())
+						return
+					}
+				}
+			}
+
+			// Sized integers (int16) are int columns with the bit width
+			if bits, ok := intBits[schema.Columns[i].Type]; ok {
+				schema.Columns[i].Type = "int"
+				schema.Columns[i].Bits = bits
+			}
+
+			loadedcols = true
+
+			continue
+		}
+	}
+
+	// Unknown types, negative lengths, scales greater than the precision and duplicate names make no sense
+	if Error = schema.SelfCheck(); Error != nil {
+		return
+	}
+
+	for _, c := range schema.Columns {
+		// Lengths and precisions of zero are valid, but they leave little room for values
+		switch {
+		case c.Type == "string" && c.Length == 0:
+			Warnings = append(Warnings, fmt.Sprintf("column %s: string without a length only accepts empty values", c.Name))
+		case c.Type == "decimal" && c.Precision == 0:
+			Warnings = append(Warnings, fmt.Sprintf("column %s: decimal without a precision only accepts zero", c.Name))
+		}
+	}
+
+	// It makes no sense of the schema does not contain columns
+	schema.isloaded = loadedcols
+
+	return
+}
+
+// SchemaErrors - problems of a schema found by SelfCheck
+type SchemaErrors []error
+
+// Error - implements the error interface. Each problem is on its own line.
+func (se SchemaErrors) Error() string {
+	errs := make([]string, len(se))
+	for i, err := range se {
+		errs[i] = err.Error()
+	}
+	return strings.Join(errs, "\n")
+}
+
+// SelfCheck - checks the schema for consistency before it is used. It returns SchemaErrors with every
+// problem found: no columns, a delimiter that is not a single character, an unknown charset or mode,
+// unknown column types, negative lengths, scales greater than the precision, defaults that are not valid
+// values of their column and duplicate column names.
+func (sch *Schema) SelfCheck() error {
+
+	var errs SchemaErrors
+
+	if len(sch.Columns) == 0 {
+		errs = append(errs, errors.New("schema has no columns"))
+	}
+
+	if _, err := sch.comma(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if _, err := sch.charset(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if sch.Mode != "" && sch.Mode != ModeDelimited && sch.Mode != ModeFixed {
+		errs = append(errs, fmt.Errorf("unknown mode %q", sch.Mode))
+	}
+
+	// Column names must be unique for name based lookups
+	names := make(map[string]bool, len(sch.Columns))
+	for i := range sch.Columns {
+		c := &sch.Columns[i]
+		if err := c.check(); err != nil {
+			errs = append(errs, err)
+		} else if err = sch.checkDefault(c); err != nil {
+			errs = append(errs, err)
+		}
+
+		n := strings.ToLower(c.Name)
+		if names[n] {
+			errs = append(errs, fmt.Errorf("duplicate column name %q", c.Name))
+		}
+		names[n] = true
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// checkDefault - checks that the default of the column passes the validation of the column, since it takes
+// the place of empty values. It is checked with the settings of the schema, like the decimal separator.
+func (sch *Schema) checkDefault(sc *SchemaColumn) error {
+	if sc.Default == "" {
+		return nil
+	}
+
+	one := sch.Clone()
+	one.Columns = []SchemaColumn{sc.clone()}
+	one.Columns[0].Transforms = nil // the default is not transformed
+	one.PrimaryKey = nil
+	one.StrictColumnCount = false
+	one.TrimFields = false
+
+	if errs := one.validateRecord(one.compiled(), 0, []string{sc.Default}, 1, nil); len(errs) > 0 {
+		return fmt.Errorf("column %q has a default %q that fails the %s check", sc.Name, sc.Default, errs[0].Kind)
+	}
+
+	return nil
+}
+
+// AddColumn - checks the column and appends it to the schema. The column must be consistent and its name
+// must not be the name of another column in any case. The compiled column data is reset, but the schema
+// should still not be modified while it is in use.
+func (sch *Schema) AddColumn(col SchemaColumn) error {
+	if err := col.check(); err != nil {
+		return err
+	}
+
+	if col.Pattern != "" {
+		if _, err := compilePattern(col.Pattern); err != nil {
+			return fmt.Errorf("Column %s: %s", col.Name, err.Error())
+		}
+	}
+
+	if err := sch.checkDefault(&col); err != nil {
+		return err
+	}
+
+	for _, c := range sch.Columns {
+		if strings.EqualFold(c.Name, col.Name) {
+			return fmt.Errorf("duplicate column name %q", col.Name)
+		}
+	}
+
+	sch.Columns = append(sch.Columns, col.clone())
+	sch.isloaded = true
+	sch.ResetCache()
+
+	return nil
+}
+
+// knownProperties - properties of the schema header
+var knownProperties = []string{"ver", "hdr", "del", "cs", "mode"}
+
+// suggestProperty - returns a hint of the known property that a misspelled key (delim, VER) was meant to be
+func suggestProperty(key string) string {
+	lk := strings.ToLower(key)
+	for _, p := range knownProperties {
+		if lk != "" && (strings.HasPrefix(lk, p) || strings.HasPrefix(p, lk)) {
+			return fmt.Sprintf(", did you mean %q?", p)
+		}
+	}
+	return ""
+}
+
+// delimiter - returns the delimiter of the schema. An empty delimiter is a comma.
+func (sch *Schema) delimiter() string {
+	if sch.Delimiter == "" {
+		return ","
+	}
+	return sch.Delimiter
+}
+
+// mode - returns the mode of the schema. An empty mode is delimited.
+func (sch *Schema) mode() string {
+	if sch.Mode == "" {
+		return ModeDelimited
+	}
+	return sch.Mode
+}
+
+// comma - returns the delimiter of the schema as a rune. An empty delimiter defaults to comma.
+func (sch *Schema) comma() (rune, error) {
+	if sch.Delimiter == "" {
+		return ',', nil
+	}
+
+	if utf8.RuneCountInString(sch.Delimiter) != 1 {
+		return 0, fmt.Errorf(`Delimiter %q must be exactly one character`, sch.Delimiter)
+	}
+
+	c, _ := utf8.DecodeRuneInString(sch.Delimiter)
+	return c, nil
+}
+
+// ValidateReturn - data by the schema. This is just a basic validation function.
+// It wraps Validate and joins the validation errors into a single error.
+func (sch *Schema) ValidateReturn(data []byte) (Records [][]string, Error error) {
+
+	var errs []ValidationError
+	Records, errs = sch.Validate(data)
+	Error = joinErrors(errs)
+
+	return
+}
+
+// joinErrors - returns the validation errors as a single error, or nil if there is none
+func joinErrors(errs []ValidationError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	// Parse and header errors are returned as is
+	if errs[0].Kind == "parse" || errs[0].Kind == "header" {
+		return errs[0].Err
+	}
+
+	return ValidationErrors(errs)
+}
+
+// PrintSchema - print schema to string. This is a basic function. We can improve it later.
+func (sch *Schema) PrintSchema() string {
+	schs := sch.printProperties() + "; "
+
+	return schs + sch.printColumns(false, false)
+}
+
+// printProperties - prints the property section of the schema. The charset and the mode are printed only
+// when they are set.
+func (sch *Schema) printProperties() string {
+	props := fmt.Sprintf("ver:%s,hdr:%t,del:%s", sch.Version, sch.WithHeader, printDelimiter(sch.delimiter()))
+	if sch.Charset != "" {
+		props += ",cs:" + sch.Charset
+	}
+	if sch.Mode != "" && sch.Mode != ModeDelimited {
+		props += ",mode:" + sch.Mode
+	}
+	return props
+}
+
+// PrintSchemaCompact - print schema to string like PrintSchema, but string columns with the default
+// length of 4000 and nothing else are printed by their name only, as they were in the shorthand schema.
+// Parsing the output gives the same schema.
+func (sch *Schema) PrintSchemaCompact() string {
+	schs := sch.printProperties() + "; "
+
+	return schs + sch.printColumns(false, true)
 }
 
-// ParseSchema - parse WebCSV schema. This is a basic function. We can improve it later.
-func ParseSchema(raw string) (schema *Schema, Error error) {
-	schema = &Schema{
-		isloaded: false,
+// PrintSchemaCanonical - print schema to a normalized string without the version. Schemas that are
+// the same regardless of version and name case print the same, so the output is suitable for diffing
+// and hashing. The charset and the mode are printed when they are not the defaults.
+func (sch *Schema) PrintSchemaCanonical() string {
+	props := fmt.Sprintf("hdr:%t,del:%s", sch.WithHeader, printDelimiter(sch.delimiter()))
+	if cs := sch.canonicalCharset(); cs != "" {
+		props += ",cs:" + cs
+	}
+	if sch.mode() != ModeDelimited {
+		props += ",mode:" + sch.mode()
 	}
+	return props + ";" + sch.printColumns(true, false)
+}
 
-	// split the header
-	parts := strings.Split(raw, `;`)
+// Fingerprint - SHA-256 hash in hexadecimal of the canonical form of the schema. Schemas that print
+// the same canonical form have the same fingerprint.
+func (sch *Schema) Fingerprint() string {
+	sum := sha256.Sum256([]byte(sch.PrintSchemaCanonical()))
+	return hex.EncodeToString(sum[:])
+}
 
-	// First part: schema properties. These are separated by comma
-	prop := strings.Split(parts[0], `,`)
-	for _, v := range prop {
-		kv := strings.Split(v, `:`)
-		switch strings.TrimSpace(kv[0]) {
-		case "ver":
-			schema.Version = kv[1]
-		case "hdr":
-			schema.WithHeader, _ = strconv.ParseBool(kv[1])
-		case "del":
-			schema.Delimiter = kv[1]
-			if schema.Delimiter == "" {
-				schema.Delimiter = ","
+// printColumns - prints the column section of the schema. Canonical columns have lower case names.
+// Compact columns omit the type of plain string(4000) columns.
+func (sch *Schema) printColumns(canonical bool, compact bool) string {
+
+	schs := ""
+	cma := ""
+	for _, c := range sch.Columns {
+
+		if canonical {
+			c.Name = strings.ToLower(c.Name)
+		}
+
+		// a bare name is parsed as string(4000)
+		if compact && c.isShorthand() {
+			schs += cma + c.Name
+			cma = ","
+			continue
+		}
+
+		if c.Name != "" {
+			schs += cma + c.Name + ":"
+		}
+
+		schs += c.Type
+
+		// an int with a width is printed as its sized type (int16)
+		if c.Type == "int" && c.Bits != 0 {
+			schs += strconv.Itoa(c.Bits)
+		}
+
+		if c.Type == "string" {
+			schs += fmt.Sprintf("(%d)", c.Length)
+		}
+
+		if c.Type == "email" && c.Length > 0 {
+			schs += fmt.Sprintf("(%d)", c.Length)
+		}
+
+		if c.Type == "decimal" {
+			schs += fmt.Sprintf("(%d,%d)", c.Precision, c.Scale)
+		}
+
+		if c.Scientific {
+			schs += "e"
+		}
+
+		// other types have no parameters but the length, which is kept when it is set
+		switch c.Type {
+		case "int", "bool", "uuid":
+			if c.Length > 0 {
+				schs += fmt.Sprintf("(%d)", c.Length)
+			} else if c.Unit != "" {
+				schs += "()"
 			}
 		}
-	}
 
-	// Second part: schema columns.
-	schp := parts[1]
-	schpart := make([]rune, len(schp))
-	// check for commas inside parenthesis
-	openp := -1
-	closep := -1
-	commapos := -1
-	for i, c := range schp {
+		if c.Unit != "" {
 
-		schpart[i] = c
+			// a unit starting with e would be read as scientific notation
+			if c.Scientific || strings.HasPrefix(c.Unit, "e") {
+				schs += `"` + c.Unit + `"`
+			} else {
+				schs += c.Unit
+			}
+		}
 
-		if c == '(' {
-			openp = i
+		if c.Format != "" {
+			schs += "(" + c.Format + ")"
 		}
 
-		if c == ',' {
-			commapos = i
+		if c.Type == "enum" {
+			schs += "(" + strings.Join(c.Choices, ";") + ")"
 		}
 
-		if c == ')' {
-			closep = i
+		if c.Pattern != "" {
+			schs += "/" + escapeSlashes(c.Pattern) + "/"
 		}
 
-		if openp != -1 && closep != 1 && commapos != -1 && openp < closep && commapos < closep && commapos > openp {
-			schpart[commapos] = ';' // since semicolon was already parsed, we can set the delimiter temporarily to ;
-			commapos = -1
-			openp = -1
-			closep = -1
+		if c.Min != nil || c.Max != nil {
+			schs += "[" + c.printMin() + ".." + c.printMax() + "]"
 		}
 
-	}
+		if c.MinDate != "" || c.MaxDate != "" {
+			schs += "[" + c.MinDate + ".." + c.MaxDate + "]"
+		}
 
-	sch := strings.Split(string(schpart), `,`)
-	schema.Columns = make([]SchemaColumn, len(sch))
+		if c.Nullable {
+			schs += "?"
+		}
 
-	if len(sch) == 0 {
-		Error = errors.New(`No schema defined`)
-		return
+		if c.Required {
+			schs += "!"
+		}
+
+		if c.Start > 0 {
+			schs += "@" + strconv.Itoa(c.Start)
+		}
+
+		if c.Default != "" {
+			schs += "=" + escapeDefault(c.Default)
+		}
+
+		if len(c.Transforms) > 0 {
+			schs += "|" + strings.Join(c.Transforms, "|")
+		}
+
+		cma = ","
 	}
 
-	loadedcols := false
+	return schs
+}
 
-	for i, v := range sch {
+// WriteTo - writes the printed schema to w. It satisfies io.WriterTo.
+func (sch *Schema) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, sch.PrintSchema())
+	return int64(n), err
+}
 
-		// get name and value
-		nv := strings.Split(v, `:`)
+// ReadSchema - reads and parses a schema from r
+func ReadSchema(r io.Reader) (*Schema, error) {
+	raw, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
 
-		name := strings.TrimSpace(nv[0])
+	return ParseSchema(strings.TrimSpace(string(raw)))
+}
 
-		// A column with one element will be treated as:
-		// - Column name
-		// - string as default type
-		// - maximum length of 4000
-		if len(nv) == 1 {
-			schema.Columns[i].Name = name
-			schema.Columns[i].Type = "string"
-			schema.Columns[i].Length = 4000
+// Clone - returns a deep copy of the schema. The copy can be modified without affecting the original.
+func (sch *Schema) Clone() *Schema {
+	c := &Schema{
+		Version:             sch.Version,
+		WithHeader:          sch.WithHeader,
+		Delimiter:           sch.Delimiter,
+		VersionPolicy:       sch.VersionPolicy,
+		CaseInsensitive:     sch.CaseInsensitive,
+		FailFast:            sch.FailFast,
+		ApplyDefaults:       sch.ApplyDefaults,
+		OmitHeader:          sch.OmitHeader,
+		VerifyHeader:        sch.VerifyHeader,
+		MapByHeader:         sch.MapByHeader,
+		HeaderCaseSensitive: sch.HeaderCaseSensitive,
+		StrictColumnCount:   sch.StrictColumnCount,
+		StrictTrailingRow:   sch.StrictTrailingRow,
+		ByteLength:          sch.ByteLength,
+		ExactDecimal:        sch.ExactDecimal,
+		DecimalSeparator:    sch.DecimalSeparator,
+		ThousandsSeparator:  sch.ThousandsSeparator,
+		CommentChar:         sch.CommentChar,
+		LazyQuotes:          sch.LazyQuotes,
+		Mode:                sch.Mode,
+		BackslashEscape:     sch.BackslashEscape,
+		FieldsPerRecord:     sch.FieldsPerRecord,
+		TrimFields:          sch.TrimFields,
+		Normalize:           sch.Normalize,
+		StripUnits:          sch.StripUnits,
+		Migrator:            sch.Migrator,
+		MaxRows:             sch.MaxRows,
+		MaxBytes:            sch.MaxBytes,
+		Charset:             sch.Charset,
+		isloaded:            sch.isloaded,
+	}
 
-			loadedcols = true
-			continue
+	if sch.PrimaryKey != nil {
+		c.PrimaryKey = append([]string(nil), sch.PrimaryKey...)
+	}
+	if sch.TrueValues != nil {
+		c.TrueValues = append([]string(nil), sch.TrueValues...)
+	}
+	if sch.FalseValues != nil {
+		c.FalseValues = append([]string(nil), sch.FalseValues...)
+	}
+
+	if sch.Columns != nil {
+		c.Columns = make([]SchemaColumn, len(sch.Columns))
+		for i, col := range sch.Columns {
+			c.Columns[i] = col.clone()
 		}
+	}
 
-		// A column with two elements
-		if len(nv) == 2 {
-			schema.Columns[i].Length = 0
-			schema.Columns[i].Precision = 0
-			schema.Columns[i].Scale = 0
-			schema.Columns[i].Name = name
+	return c
+}
 
-			// extract length if there is any
-			col := strings.ToLower(strings.TrimSpace(nv[1]))
-			schema.Columns[i].Type = col
+// clone - returns a deep copy of the column
+func (sc SchemaColumn) clone() SchemaColumn {
+	if sc.Choices != nil {
+		sc.Choices = append([]string(nil), sc.Choices...)
+	}
+	if sc.Transforms != nil {
+		sc.Transforms = append([]string(nil), sc.Transforms...)
+	}
+	if sc.Min != nil {
+		min := *sc.Min
+		sc.Min = &min
+	}
+	if sc.Max != nil {
+		max := *sc.Max
+		sc.Max = &max
+	}
+	if sc.Index != nil {
+		idx := *sc.Index
+		sc.Index = &idx
+	}
+	return sc
+}
 
-			// remove parenthesis
-			col = strings.ReplaceAll(col, "(", " ")
-			col = strings.ReplaceAll(col, ")", "")
+// Equal - checks if every exported field of the other schema is exactly the same, unlike IsValid which
+// checks for compatibility.
+func (sch *Schema) Equal(other *Schema) bool {
+	if other == nil {
+		return false
+	}
 
-			// first instance of space could be the space left by the opening parenthesis '('
-			pos := strings.Index(col, ` `)
-			lps := ""
-			if pos != -1 {
-				schema.Columns[i].Type = col[0:pos] // type name
-
-				lps = col[pos+1:] // get length or precision and scale
-
-				// check if the type has comma. A comma represents the precision and scale.
-				// If there is no comma (semicolon replaced it earlier), it is just the length
-				pos = strings.Index(lps, `;`)
-				if pos != -1 {
-					schema.Columns[i].Precision, _ = strconv.Atoi(lps[0:pos])
-					schema.Columns[i].Scale, _ = strconv.Atoi(lps[pos+1:])
-				} else {
-					schema.Columns[i].Length, _ = strconv.Atoi(lps)
-				}
-			}
+	if sch.Version != other.Version ||
+		sch.WithHeader != other.WithHeader ||
+		sch.Delimiter != other.Delimiter ||
+		sch.VersionPolicy != other.VersionPolicy ||
+		sch.CaseInsensitive != other.CaseInsensitive ||
+		sch.FailFast != other.FailFast ||
+		sch.ApplyDefaults != other.ApplyDefaults ||
+		sch.OmitHeader != other.OmitHeader ||
+		sch.VerifyHeader != other.VerifyHeader ||
+		sch.MapByHeader != other.MapByHeader ||
+		sch.HeaderCaseSensitive != other.HeaderCaseSensitive ||
+		sch.StrictColumnCount != other.StrictColumnCount ||
+		sch.StrictTrailingRow != other.StrictTrailingRow ||
+		sch.ByteLength != other.ByteLength ||
+		sch.ExactDecimal != other.ExactDecimal ||
+		sch.DecimalSeparator != other.DecimalSeparator ||
+		sch.ThousandsSeparator != other.ThousandsSeparator ||
+		sch.CommentChar != other.CommentChar ||
+		sch.LazyQuotes != other.LazyQuotes ||
+		sch.Mode != other.Mode ||
+		sch.BackslashEscape != other.BackslashEscape ||
+		sch.FieldsPerRecord != other.FieldsPerRecord ||
+		sch.TrimFields != other.TrimFields ||
+		sch.Normalize != other.Normalize ||
+		sch.StripUnits != other.StripUnits ||
+		!equalStrings(sch.PrimaryKey, other.PrimaryKey) ||
+		sch.MaxRows != other.MaxRows ||
+		sch.MaxBytes != other.MaxBytes ||
+		sch.Charset != other.Charset ||
+		!equalStrings(sch.TrueValues, other.TrueValues) ||
+		!equalStrings(sch.FalseValues, other.FalseValues) {
+		return false
+	}
 
-			loadedcols = true
+	if len(sch.Columns) != len(other.Columns) {
+		return false
+	}
 
-			continue
+	for i := range sch.Columns {
+		if !sch.Columns[i].equal(&other.Columns[i]) {
+			return false
 		}
 	}
 
-	// It makes no sense of the schema does not contain columns
-	schema.isloaded = loadedcols
+	return true
+}
 
-	return
+// equalStrings - checks if both slices have the same values in the same order
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
 }
 
-// ValidateReturn - data by the schema. This is just a basic validation function.
-func (sch *Schema) ValidateReturn(data []byte) (Records [][]string, Error error) {
+// equal - checks if every field of the other column is exactly the same
+func (sc *SchemaColumn) equal(o *SchemaColumn) bool {
+	if sc.Name != o.Name ||
+		sc.Type != o.Type ||
+		sc.Length != o.Length ||
+		sc.Precision != o.Precision ||
+		sc.Scale != o.Scale ||
+		sc.Nullable != o.Nullable ||
+		sc.Format != o.Format ||
+		sc.Pattern != o.Pattern ||
+		sc.Default != o.Default ||
+		sc.Scientific != o.Scientific ||
+		sc.Unit != o.Unit ||
+		sc.Start != o.Start ||
+		sc.Bits != o.Bits ||
+		!equalStrings(sc.Transforms, o.Transforms) ||
+		sc.MinDate != o.MinDate ||
+		sc.MaxDate != o.MaxDate ||
+		sc.Required != o.Required {
+		return false
+	}
 
-	// Data will be parsed as CSV
-	r := csv.NewReader(bytes.NewReader(data))
-	Records, Error = r.ReadAll()
+	if !equalBound(sc.Min, o.Min) || !equalBound(sc.Max, o.Max) || !equalIndex(sc.Index, o.Index) {
+		return false
+	}
 
-	if Error != nil {
-		return
+	if len(sc.Choices) != len(o.Choices) {
+		return false
 	}
 
-	var (
-		err      error
-		errorstr string
-		sc       *SchemaColumn
-	)
+	for i := range sc.Choices {
+		if sc.Choices[i] != o.Choices[i] {
+			return false
+		}
+	}
 
-	// Validate each line and column
-	for i, rec := range Records {
+	return true
+}
 
-		for cn, cv := range rec {
+// IsValid - checks if the supplied schema is the same
+func (sch *Schema) IsValid(ext *Schema) bool {
+	return len(sch.Diff(ext)) == 0
+}
 
-			sc = &sch.Columns[cn]
+// Diff - returns the differences of the supplied schema in human readable form. Equal schemas return an empty slice.
+func (sch *Schema) Diff(ext *Schema) []string {
 
-			switch sc.Type {
-			case "string":
-				// Check if the value exceeds the length
-				if len(cv) > sc.Length {
-					errorstr += fmt.Sprintf("Column %d of line %d exceeds specified column length of %d\n", cn, i+1, sc.Length)
-					continue
-				}
-			case "int":
-				// Check if the value can be converted to int
-				_, err = strconv.ParseInt(cv, 10, 64)
-				if err != nil {
-					errorstr += fmt.Sprintf("Column %d of line %d could not be converted to integer. Error: %s\n", cn, i+1, err.Error())
-					continue
-				}
+	diff := sch.diffProperties(ext)
 
-			case "bool":
-				// Check if the value can be converted to boolean
-				_, err = strconv.ParseBool(cv)
-				if err != nil {
-					errorstr += fmt.Sprintf("Column %d of line %d could not be converted to boolean. Error: %s\n", cn, i+1, err.Error())
-					continue
-				}
+	cnt := len(sch.Columns)
+	if cnt != len(ext.Columns) {
+		diff = append(diff, fmt.Sprintf("column count mismatch: %d vs %d", cnt, len(ext.Columns)))
+		if len(ext.Columns) < cnt {
+			cnt = len(ext.Columns)
+		}
+	}
 
-			case "date":
-				// Check if the value can be converted to date
-				_, err = time.Parse("2006-01-02", cv)
-				if err != nil {
-					errorstr += fmt.Sprintf("Column %d of line %d could not be converted to date. Error: %s\n", cn, i+1, err.Error())
-					continue
-				}
-			case "datetime":
-				// Check if the value can be converted to datetime
-				_, err = time.Parse(time.RFC3339, cv)
-				if err != nil {
-					errorstr += fmt.Sprintf("Column %d of line %d could not be converted to datetime. Error: %s\n", cn, i+1, err.Error())
-					continue
-				}
-			case "decimal":
-
-				whl := ""
-				dec := ""
-				// get decimal point and whole number. This is just the . being parsed.
-				pos := strings.Index(cv, `.`)
-
-				// a whole number?
-				if pos == -1 {
-					whl = cv
-					dec = strings.Repeat(`0`, sc.Scale)
-
-					// check if the whole number length plus the schema scale  sums up
-					if len(cv) > sc.Precision-sc.Scale {
-						errorstr += fmt.Sprintf("Column %d of line %d is not a valid decimal scale as specified by the schema. \n", cn, i+1)
-						continue
-					}
+	for i := 0; i < cnt; i++ {
+		sc, ec := &sch.Columns[i], &ext.Columns[i]
 
-					cv = whl + `.` + dec
-				}
+		if strings.ToLower(sc.Name) != strings.ToLower(ec.Name) {
+			diff = append(diff, fmt.Sprintf("column %d name mismatch: %s vs %s", i, sc.Name, ec.Name))
+		}
+		diff = append(diff, sc.diff(i, ec)...)
+	}
 
-				// decimal?
-				if pos != -1 {
-					whl = cv[0:pos]
-					dec = cv[pos+1:]
+	return diff
+}
 
-					// Trim to scale
-					if len(dec) > sc.Scale {
-						dec = dec[0:sc.Scale]
-					} else {
-						dec = dec + strings.Repeat(`0`, sc.Scale-len(dec)) // pad the remaining with zero
-					}
+// IsValidUnordered - checks if the supplied schema is the same, but with the columns in any order. Columns
+// are matched by name regardless of case. Data in another order can only be read by the names of its header,
+// so columns in another order are a difference if the supplied schema has no header.
+func (sch *Schema) IsValidUnordered(ext *Schema) bool {
+	return len(sch.DiffUnordered(ext)) == 0
+}
 
-					// check decimal if this can be converted to a number
-					_, err = strconv.ParseInt(dec, 10, 64)
-					if err != nil {
-						errorstr += fmt.Sprintf("Column %d of line %d contains an invalid decimal scale as specified by the schema. \n", cn, i+1)
-						continue
-					}
+// DiffUnordered - returns the differences of the supplied schema like Diff, but the columns are matched by
+// name instead of position
+func (sch *Schema) DiffUnordered(ext *Schema) []string {
 
-					// check if the length of the  whole number is valid
-					if len(whl) > sc.Precision {
-						errorstr += fmt.Sprintf("Column %d of line %d exceeds the whole number length as specified by the schema. \n", cn, i+1)
-						continue
-					}
+	diff := sch.diffProperties(ext)
 
-					cv = whl + `.` + dec // fix
-				}
+	matched := make([]bool, len(ext.Columns))
+	moved := false
+	for i := range sch.Columns {
+		sc := &sch.Columns[i]
 
-				// Check if the value can be converted to decimal
-				_, err = strconv.ParseFloat(cv, 64)
-				if err != nil {
-					errorstr += fmt.Sprintf("Column %d of line %d could not be converted to decimal. Error: %s\n", cn, i+1, err.Error())
-					continue
-				}
+		j := -1
+		for k := range ext.Columns {
+			if !matched[k] && strings.EqualFold(sc.Name, ext.Columns[k].Name) {
+				j = k
+				break
 			}
 		}
 
-		if errorstr != "" {
-			break
+		if j == -1 {
+			diff = append(diff, fmt.Sprintf("column %d missing: %s", i, sc.Name))
+			continue
 		}
+		matched[j] = true
+		moved = moved || i != j
 
+		diff = append(diff, sc.diff(i, &ext.Columns[j])...)
 	}
 
-	if errorstr != "" {
-		Error = errors.New(errorstr)
-	} else {
-		Error = nil
+	for j, ec := range ext.Columns {
+		if !matched[j] {
+			diff = append(diff, fmt.Sprintf("column %s is not in the schema", ec.Name))
+		}
 	}
 
-	return
+	if moved && !ext.WithHeader {
+		diff = append(diff, "column order mismatch without a header")
+	}
+
+	return diff
 }
 
-// PrintSchema - print schema to string. This is a basic function. We can improve it later.
-func (sch *Schema) PrintSchema() string {
-	schs := fmt.Sprintf("ver:%s,hdr:%t,del:%s", sch.Version, sch.WithHeader, sch.Delimiter) + "; "
+// diffProperties - returns the differences of the properties of the supplied schema
+func (sch *Schema) diffProperties(ext *Schema) []string {
 
-	cma := ""
-	for _, c := range sch.Columns {
+	diff := make([]string, 0)
 
-		if c.Name != "" {
-			schs += cma + c.Name + ":"
-		}
+	if !sch.compatibleVersion(ext.Version) {
+		diff = append(diff, fmt.Sprintf("version mismatch: %s vs %s", sch.Version, ext.Version))
+	}
 
-		schs += c.Type
+	if sch.WithHeader != ext.WithHeader {
+		diff = append(diff, fmt.Sprintf("header mismatch: %t vs %t", sch.WithHeader, ext.WithHeader))
+	}
 
-		if c.Type == "string" {
-			schs += fmt.Sprintf("(%d)", c.Length)
-		}
+	if sch.delimiter() != ext.delimiter() {
+		diff = append(diff, fmt.Sprintf("delimiter mismatch: %s vs %s", escape(sch.delimiter()), escape(ext.delimiter())))
+	}
 
-		if c.Type == "decimal" {
-			schs += fmt.Sprintf("(%d,%d)", c.Precision, c.Scale)
-		}
+	if sch.mode() != ext.mode() {
+		diff = append(diff, fmt.Sprintf("mode mismatch: %s vs %s", sch.mode(), ext.mode()))
+	}
 
-		cma = ","
+	if !sch.sameCharset(ext) {
+		diff = append(diff, fmt.Sprintf("charset mismatch: %s vs %s", sch.Charset, ext.Charset))
 	}
 
-	return schs
+	return diff
 }
 
-// IsValid - checks if the supplied schema is the same
-func (sch *Schema) IsValid(ext *Schema) bool {
+// sameCharset - checks if the input is decoded the same by the charsets of both schemas, so the names
+// of a charset (latin1, iso-8859-1) are the same. Unknown charsets are compared by their names.
+func (sch *Schema) sameCharset(ext *Schema) bool {
+	enc, err := sch.charset()
+	eenc, eerr := ext.charset()
+	if err != nil || eerr != nil {
+		return strings.EqualFold(strings.TrimSpace(sch.Charset), strings.TrimSpace(ext.Charset))
+	}
+	return enc == eenc
+}
 
-	if strings.ToLower(sch.Version) != strings.ToLower(ext.Version) {
-		return false
+// diff - returns the differences of the definition of the supplied column, other than the name.
+// The columns are reported by the index i.
+func (sc *SchemaColumn) diff(i int, ec *SchemaColumn) []string {
+
+	var diff []string
+
+	if sc.Type != ec.Type {
+		diff = append(diff, fmt.Sprintf("column %d type mismatch: %s vs %s", i, sc.Type, ec.Type))
+	}
+	if sc.Length != ec.Length {
+		diff = append(diff, fmt.Sprintf("column %d length mismatch: %d vs %d", i, sc.Length, ec.Length))
+	}
+	if sc.Bits != ec.Bits {
+		diff = append(diff, fmt.Sprintf("column %d bits mismatch: %d vs %d", i, sc.Bits, ec.Bits))
+	}
+	if sc.Precision != ec.Precision {
+		diff = append(diff, fmt.Sprintf("column %d precision mismatch: %d vs %d", i, sc.Precision, ec.Precision))
+	}
+	if sc.Scale != ec.Scale {
+		diff = append(diff, fmt.Sprintf("column %d scale mismatch: %d vs %d", i, sc.Scale, ec.Scale))
+	}
+	if sc.Start != ec.Start {
+		diff = append(diff, fmt.Sprintf("column %d start mismatch: %d vs %d", i, sc.Start, ec.Start))
+	}
+	if sc.Format != ec.Format {
+		diff = append(diff, fmt.Sprintf("column %d format mismatch: %s vs %s", i, sc.Format, ec.Format))
+	}
+	if strings.Join(sc.Choices, ";") != strings.Join(ec.Choices, ";") {
+		diff = append(diff, fmt.Sprintf("column %d choices mismatch: %s vs %s", i, strings.Join(sc.Choices, ";"), strings.Join(ec.Choices, ";")))
+	}
+	if sc.Pattern != ec.Pattern {
+		diff = append(diff, fmt.Sprintf("column %d pattern mismatch: %s vs %s", i, sc.Pattern, ec.Pattern))
+	}
+	if sc.Scientific != ec.Scientific {
+		diff = append(diff, fmt.Sprintf("column %d scientific mismatch: %t vs %t", i, sc.Scientific, ec.Scientific))
+	}
+	if strings.Join(sc.Transforms, "|") != strings.Join(ec.Transforms, "|") {
+		diff = append(diff, fmt.Sprintf("column %d transforms mismatch: %s vs %s", i, strings.Join(sc.Transforms, "|"), strings.Join(ec.Transforms, "|")))
+	}
+	if sc.MinDate != ec.MinDate || sc.MaxDate != ec.MaxDate {
+		diff = append(diff, fmt.Sprintf("column %d date range mismatch: [%s,%s] vs [%s,%s]", i, sc.MinDate, sc.MaxDate, ec.MinDate, ec.MaxDate))
+	}
+	if sc.Unit != ec.Unit {
+		diff = append(diff, fmt.Sprintf("column %d unit mismatch: %s vs %s", i, sc.Unit, ec.Unit))
+	}
+	if sc.Default != ec.Default {
+		diff = append(diff, fmt.Sprintf("column %d default mismatch: %s vs %s", i, sc.Default, ec.Default))
+	}
+	if sc.Required != ec.Required {
+		diff = append(diff, fmt.Sprintf("column %d required mismatch: %t vs %t", i, sc.Required, ec.Required))
+	}
+	if sc.Nullable != ec.Nullable {
+		diff = append(diff, fmt.Sprintf("column %d nullable mismatch: %t vs %t", i, sc.Nullable, ec.Nullable))
+	}
+	if !equalBound(sc.Min, ec.Min) || !equalBound(sc.Max, ec.Max) {
+		diff = append(diff, fmt.Sprintf("column %d range mismatch: [%s,%s] vs [%s,%s]", i, sc.printMin(), sc.printMax(), ec.printMin(), ec.printMax()))
+	}
+	if !equalIndex(sc.Index, ec.Index) {
+		diff = append(diff, fmt.Sprintf("column %d index mismatch: %s vs %s", i, printIndex(sc.Index), printIndex(ec.Index)))
 	}
 
-	if sch.WithHeader != ext.WithHeader {
-		return false
+	return diff
+}
+
+// printIndex - prints the index of a column, or none if it is not set
+func printIndex(idx *int) string {
+	if idx == nil {
+		return "none"
 	}
+	return strconv.Itoa(*idx)
+}
 
-	if sch.Delimiter != ext.Delimiter {
-		return false
+// escape - shows special characters like tab in escaped form
+func escape(v string) string {
+	q := strconv.Quote(v)
+	return q[1 : len(q)-1]
+}
+
+// compatibleVersion - checks the version against the version policy of the schema.
+// Versions that are not numeric (major.minor.patch) are compared as they are.
+func (sch *Schema) compatibleVersion(ver string) bool {
+
+	sv, sok := parseVersion(sch.Version)
+	ev, eok := parseVersion(ver)
+	if !sok || !eok {
+		return strings.ToLower(sch.Version) == strings.ToLower(ver)
 	}
 
-	cnt := len(sch.Columns)
-	if cnt != len(ext.Columns) {
-		return false
+	switch sch.VersionPolicy {
+	case VersionExact:
+		return sv == ev
+	case VersionMinorCompatible:
+		return sv[0] == ev[0] && ev[1] <= sv[1]
 	}
 
-	for i := 0; i < cnt; i++ {
-		if strings.ToLower(sch.Columns[i].Name) != strings.ToLower(ext.Columns[i].Name) {
-			return false
-		}
-		if sch.Columns[i].Length != ext.Columns[i].Length {
-			return false
-		}
-		if sch.Columns[i].Type != ext.Columns[i].Type {
-			return false
-		}
-		if sch.Columns[i].Length != ext.Columns[i].Length {
-			return false
-		}
-		if sch.Columns[i].Precision != ext.Columns[i].Precision {
-			return false
-		}
-		if sch.Columns[i].Scale != ext.Columns[i].Scale {
-			return false
+	return sv[0] == ev[0]
+}
+
+// parseVersion - parses a major.minor.patch version. Missing parts are zero.
+func parseVersion(ver string) (v [3]int, ok bool) {
+	parts := strings.Split(strings.TrimSpace(ver), `.`)
+	if len(parts) > 3 {
+		return v, false
+	}
+
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, false
 		}
+		v[i] = n
 	}
 
-	return true
+	return v, true
 }
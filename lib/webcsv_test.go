@@ -0,0 +1,234 @@
+package webcsv
+
+import (
+	"testing"
+)
+
+func TestParseSchemaDateRange(t *testing.T) {
+	sch, err := ParseSchema("ver:1.0,del:|; D:date(02/01/2006)[01/01/1900..now]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := sch.Columns[0]
+	if c.Format != "02/01/2006" || c.MinDate != "01/01/1900" || c.MaxDate != "now" {
+		t.Fatalf("got format %q and range %q..%q", c.Format, c.MinDate, c.MaxDate)
+	}
+
+	if _, err = sch.ValidateReturn([]byte("31/12/1899\n")); err == nil {
+		t.Fatal("got no error, want the date before the range rejected")
+	}
+
+	rt, err := ParseSchema(sch.PrintSchema())
+	if err != nil || !sch.Equal(rt) {
+		t.Fatalf("got %v, want %q parsed back to the same schema", err, sch.PrintSchema())
+	}
+}
+
+func TestPrintSchemaRoundTrip(t *testing.T) {
+	sch := qtySchema()
+	sch.Columns[0].Length = 20
+	sch.Columns[0].Pattern = `a/b\/c|.*,.*`
+	sch.Columns[0].Default = " a,b|c (50%) "
+	sch.Columns[1].Default = "1"
+	sch.Columns = append(sch.Columns, SchemaColumn{Name: "Note", Type: "string", Length: 10, Default: "x|y"})
+
+	raw := sch.PrintSchema()
+	rt, err := ParseSchema(raw)
+	if err != nil {
+		t.Fatalf("%q: %v", raw, err)
+	}
+
+	if len(rt.Columns) != 3 || rt.Columns[0].Default != sch.Columns[0].Default || rt.Columns[2].Default != "x|y" {
+		t.Fatalf("%q parsed to %+v", raw, rt.Columns)
+	}
+
+	if rt.Columns[0].Pattern != "a/b/c|.*,.*" {
+		t.Fatalf("got pattern %q, want a/b/c|.*,.*", rt.Columns[0].Pattern)
+	}
+
+	if _, err = rt.ValidateReturn([]byte("a/b/c,1,z\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	// percent signs that do not encode a character are kept
+	if rt, err = ParseSchema("ver:1.0; A:string(5)=50%"); err != nil || rt.Columns[0].Default != "50%" {
+		t.Fatalf("got %v, want the default 50%%", err)
+	}
+}
+
+func TestParseSchemaPercentProperties(t *testing.T) {
+	for raw, want := range map[string][2]string{
+		"ver:1.0%,del:%; A:int":   {"1.0%", "%"},
+		"ver:1.0,del:%2C; A:int":  {"1.0", ","},
+		"ver:1.0,del:%09; A:int":  {"1.0", "\t"},
+		"ver:1%zz,del:%7C; A:int": {"1%zz", "|"},
+	} {
+		sch, err := ParseSchema(raw)
+		if err != nil {
+			t.Errorf("%q: %v", raw, err)
+			continue
+		}
+
+		if sch.Version != want[0] || sch.Delimiter != want[1] {
+			t.Errorf("%q: got %q and %q, want %q and %q", raw, sch.Version, sch.Delimiter, want[0], want[1])
+		}
+	}
+}
+
+func TestParseSchemaRoundTrip(t *testing.T) {
+	raw := "ver:1.2,hdr:true,del:|; Code:string(10)/[A-Z]+/!,Qty:int16[0..100]=0,Price:decimal(9,2)?," +
+		"Born:date(02/01/2006),Kind:enum(a;b;c),Name:string(20)|trim|upper"
+
+	sch, err := ParseSchema(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rt, err := ParseSchema(sch.PrintSchema())
+	if err != nil {
+		t.Fatalf("%q: %v", sch.PrintSchema(), err)
+	}
+
+	if !sch.Equal(rt) || sch.Fingerprint() != rt.Fingerprint() {
+		t.Fatalf("%q parsed back to %q", sch.PrintSchema(), rt.PrintSchema())
+	}
+}
+
+func TestParseSchemaSections(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		ok   bool
+	}{
+		{"empty", "", false},
+		{"property only", "ver:1.0,hdr:false", false},
+		{"column only", "A:int,B:string(5)", false},
+		{"property without value", "ver; A:int", false},
+		{"empty property section", "; A:int", true},
+		{"both sections", "ver:1.0,hdr:false; A:int,B:string(5)", true},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseSchema(tt.raw)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: got %v, want success %t", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	idx := 1
+	tests := []struct {
+		name   string
+		change func(*Schema)
+		want   string
+	}{
+		{"equal", func(*Schema) {}, ""},
+		{"same charset", func(s *Schema) { s.Charset = "iso-8859-1" }, ""},
+		{"type", func(s *Schema) { s.Columns[1].Type = "decimal" }, "column 1 type mismatch: int vs decimal"},
+		{"delimiter", func(s *Schema) { s.Delimiter = "\t" }, `delimiter mismatch: , vs \t`},
+		{"header", func(s *Schema) { s.WithHeader = false }, "header mismatch: true vs false"},
+		{"length", func(s *Schema) { s.Columns[0].Length = 4 }, "column 0 length mismatch: 3 vs 4"},
+		{"charset", func(s *Schema) { s.Charset = "cp1252" }, "charset mismatch: latin1 vs cp1252"},
+		{"index", func(s *Schema) { s.Columns[1].Index = &idx }, "column 1 index mismatch: none vs 1"},
+	}
+
+	for _, tt := range tests {
+		sch := qtySchema()
+		sch.WithHeader = true
+		sch.Charset = "latin1"
+
+		ext := sch.Clone()
+		tt.change(ext)
+
+		diff := sch.Diff(ext)
+		if tt.want == "" && len(diff) != 0 || tt.want != "" && (len(diff) != 1 || diff[0] != tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, diff, tt.want)
+		}
+
+		if sch.IsValid(ext) != (tt.want == "") {
+			t.Errorf("%s: IsValid does not match the differences", tt.name)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*Schema)
+		same   bool
+	}{
+		{"equal", func(*Schema) {}, true},
+		{"version", func(s *Schema) { s.Version = "2.0" }, true},
+		{"name case", func(s *Schema) { s.Columns[0].Name = "CODE" }, true},
+		{"charset name", func(s *Schema) { s.Charset = "iso-8859-1" }, true},
+		{"type", func(s *Schema) { s.Columns[1].Type = "decimal" }, false},
+		{"charset", func(s *Schema) { s.Charset = "cp1252" }, false},
+		{"mode", func(s *Schema) { s.Mode = ModeFixed }, false},
+	}
+
+	for _, tt := range tests {
+		sch := qtySchema()
+		sch.Charset = "latin1"
+
+		ext := sch.Clone()
+		tt.change(ext)
+
+		if (sch.Fingerprint() == ext.Fingerprint()) != tt.same {
+			t.Errorf("%s: got %q and %q, want the same fingerprint %t", tt.name, sch.PrintSchemaCanonical(), ext.PrintSchemaCanonical(), tt.same)
+		}
+	}
+}
+
+func TestDiffUnordered(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(*Schema)
+		ordered bool
+		want    string
+	}{
+		{"equal", func(*Schema) {}, true, ""},
+		{"different order", func(s *Schema) { s.Columns[0], s.Columns[1] = s.Columns[1], s.Columns[0] }, false, ""},
+		{"name case", func(s *Schema) {
+			s.Columns[0], s.Columns[1] = s.Columns[1], s.Columns[0]
+			s.Columns[0].Name = "QTY"
+		}, false, ""},
+		{"type", func(s *Schema) {
+			s.Columns[0], s.Columns[1] = s.Columns[1], s.Columns[0]
+			s.Columns[0].Type = "decimal"
+		}, false, "column 1 type mismatch: int vs decimal"},
+		{"missing", func(s *Schema) { s.Columns = s.Columns[1:] }, false, "column 0 missing: Code"},
+		{"extra", func(s *Schema) { s.Columns = append(s.Columns, SchemaColumn{Name: "Note", Type: "string"}) }, false,
+			"column Note is not in the schema"},
+	}
+
+	for _, tt := range tests {
+		sch := qtySchema()
+		sch.WithHeader = true
+
+		ext := sch.Clone()
+		tt.change(ext)
+
+		if sch.IsValid(ext) != tt.ordered {
+			t.Errorf("%s: got IsValid %t, want %t", tt.name, !tt.ordered, tt.ordered)
+		}
+
+		diff := sch.DiffUnordered(ext)
+		if tt.want == "" && len(diff) != 0 || tt.want != "" && (len(diff) != 1 || diff[0] != tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, diff, tt.want)
+		}
+
+		if sch.IsValidUnordered(ext) != (tt.want == "") {
+			t.Errorf("%s: IsValidUnordered does not match the differences", tt.name)
+		}
+	}
+
+	// data in another order could not be read without its header
+	sch := qtySchema()
+	ext := sch.Clone()
+	ext.Columns[0], ext.Columns[1] = ext.Columns[1], ext.Columns[0]
+	if diff := sch.DiffUnordered(ext); len(diff) != 1 || diff[0] != "column order mismatch without a header" {
+		t.Errorf("got %q, want the order mismatch", diff)
+	}
+}
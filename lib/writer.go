@@ -0,0 +1,169 @@
+package webcsv
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// RecordWriter - writes records as CSV with each value formatted by its schema column, such as the scale of
+// decimals and the layout of dates. The values are separated by the schema delimiter, and a header of the
+// column names is written first if the schema has WithHeader set. Flush must be called after the last record.
+type RecordWriter struct {
+	sch    *Schema
+	cw     *csv.Writer
+	err    error                  // error of the schema delimiter, returned by every write
+	header bool                   // the header was written or is not needed
+	fields map[reflect.Type][]int // struct field index of each column by struct type
+}
+
+// NewRecordWriter - creates a writer of records to w formatted by the schema
+func (sch *Schema) NewRecordWriter(w io.Writer) *RecordWriter {
+	rw := &RecordWriter{
+		sch:    sch,
+		cw:     csv.NewWriter(w),
+		header: !sch.WithHeader,
+		fields: make(map[reflect.Type][]int),
+	}
+
+	rw.cw.Comma, rw.err = sch.comma()
+
+	return rw
+}
+
+// WriteStruct - writes the fields of the struct, or of the struct pointed by v, in the order of the schema
+// columns. Struct fields are matched to schema columns the same way DecodeInto does.
+func (rw *RecordWriter) WriteStruct(v interface{}) error {
+	rv, err := structValue(v)
+	if err != nil {
+		return err
+	}
+
+	fields, ok := rw.fields[rv.Type()]
+	if !ok {
+		fields = rw.sch.fieldMap(rv.Type())
+		rw.fields[rv.Type()] = fields
+	}
+
+	rec, err := rw.sch.formatStruct(rv, fields)
+	if err != nil {
+		return err
+	}
+
+	return rw.write(rec)
+}
+
+// WriteRecord - writes the values in the order of the schema columns. There must be a value for each column.
+// Values that are not strings are formatted by their column like struct fields, and nil values are empty.
+func (rw *RecordWriter) WriteRecord(values ...interface{}) error {
+	if len(values) != len(rw.sch.Columns) {
+		return fmt.Errorf("Record has %d values, schema defines %d columns", len(values), len(rw.sch.Columns))
+	}
+
+	rec := make([]string, len(values))
+	for cn, v := range values {
+		if v == nil {
+			continue
+		}
+
+		cv, err := rw.sch.formatValue(reflect.ValueOf(v), &rw.sch.Columns[cn])
+		if err != nil {
+			return fmt.Errorf("Column %d could not be encoded. Error: %s", cn, err.Error())
+		}
+		rec[cn] = cv
+	}
+
+	return rw.write(rec)
+}
+
+// Flush - writes the buffered records to the underlying writer. The header is written if no record was.
+func (rw *RecordWriter) Flush() error {
+	if err := rw.writeHeader(); err != nil {
+		return err
+	}
+
+	rw.cw.Flush()
+	return rw.cw.Error()
+}
+
+// writeHeader - writes the header once if the schema has WithHeader set
+func (rw *RecordWriter) writeHeader() error {
+	if rw.err != nil {
+		return rw.err
+	}
+
+	if rw.header {
+		return nil
+	}
+	rw.header = true
+
+	return rw.cw.Write(rw.sch.header())
+}
+
+// write - writes a formatted record after the header
+func (rw *RecordWriter) write(rec []string) error {
+	if err := rw.writeHeader(); err != nil {
+		return err
+	}
+
+	return rw.cw.Write(rec)
+}
+
+// FormatStruct - formats the fields of the struct, or of the struct pointed by v, as a record in the order
+// of the schema columns, the same way RecordWriter writes them
+func (sch *Schema) FormatStruct(v interface{}) ([]string, error) {
+	rv, err := structValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return sch.formatStruct(rv, sch.fieldMap(rv.Type()))
+}
+
+// structValue - returns the struct of v, or the struct pointed by v
+func structValue(v interface{}) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return rv, errors.New(`a struct or a pointer to a struct is required`)
+	}
+
+	return rv, nil
+}
+
+// formatStruct - formats the fields of the struct by the field index of each column
+func (sch *Schema) formatStruct(rv reflect.Value, fields []int) ([]string, error) {
+	rec := make([]string, len(sch.Columns))
+	for cn, fi := range fields {
+		if fi == -1 {
+			continue
+		}
+
+		cv, err := sch.formatValue(rv.Field(fi), &sch.Columns[cn])
+		if err != nil {
+			return nil, fmt.Errorf("Column %d could not be encoded from field %s. Error: %s", cn, rv.Type().Field(fi).Name, err.Error())
+		}
+		rec[cn] = cv
+	}
+
+	return rec, nil
+}
+
+// formatValue - formats a value by its schema column. Numbers are written with the unit of the column.
+func (sch *Schema) formatValue(fv reflect.Value, sc *SchemaColumn) (string, error) {
+	cv, err := sch.encodeValue(fv, sc)
+	if err != nil {
+		return "", err
+	}
+
+	if sc.Unit != "" && fv.Kind() != reflect.String {
+		cv += sc.Unit
+	}
+
+	return cv, nil
+}
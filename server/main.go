@@ -1,59 +1,93 @@
 package main
 
 import (
-	"encoding/csv"
+	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"net/http"
-	"strconv"
+	"os"
 	"strings"
-	"time"
 	webcsv "webcsv/lib"
+	webcsvhttp "webcsv/lib/http"
 
 	"github.com/gorilla/mux"
 )
 
-// Person - person type
-type Person struct {
-	Alive       bool
-	LastName    string
-	FirstName   string
-	MiddleName  string
-	Age         int
-	Weight      float64
-	Height      float64
-	DateBorn    time.Time
-	LastUpdated time.Time
-}
-
 var apiSchema *webcsv.Schema
 
-var p []Person // data of the API
+var corsOrigins = []string{"*"} // origins allowed to call the API from a browser
 
 func main() {
 
-	hp := "8000"
+	cfg, err := parseConfig(os.Args[1:], os.Getenv)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	router := mux.NewRouter()
-	router.StrictSlash(true)
+	// Define API Schema. A schema file could change the column definitions without recompiling.
+	apiSchema = defaultSchema()
+	if cfg.schema != "" {
+		if apiSchema, err = loadSchema(cfg.schema); err != nil {
+			log.Fatal(err)
+		}
+	}
 
-	router.PathPrefix("/").Handler(basicCRUDHandler())
+	// A person is identified by the whole name
+	apiSchema.PrimaryKey = []string{"LastName", "FirstName", "MiddleName"}
+
+	// This demo stores the persons in memory for us to retrieve later
+	store, err := webcsvhttp.NewMemoryStore(apiSchema)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	srv := &http.Server{
-		Addr:    ":" + hp,
-		Handler: router,
+		Addr:    cfg.addr,
+		Handler: newRouter(store),
+	}
+
+	log.Println("Listening at " + cfg.addr + "...")
+	log.Fatal(srv.ListenAndServe())
+}
+
+// config - settings of the server
+type config struct {
+	addr   string // address to listen at
+	schema string // path of the schema file
+}
+
+// parseConfig - reads the settings from the command line arguments and the environment.
+// The -addr flag takes precedence over the PORT environment variable. The default address is :8000.
+func parseConfig(args []string, getenv func(string) string) (config, error) {
+	var cfg config
+
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	fs.StringVar(&cfg.addr, "addr", "", "address to listen at, such as :8000")
+	fs.StringVar(&cfg.schema, "schema", "", "path of the API schema file")
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
 	}
 
-	// Define API Schema
-	apiSchema = &webcsv.Schema{
+	if cfg.addr == "" {
+		cfg.addr = ":8000"
+		if port := getenv("PORT"); port != "" {
+			cfg.addr = ":" + port
+		}
+	}
+
+	return cfg, nil
+}
+
+// defaultSchema - the API schema when there is no schema file
+func defaultSchema() *webcsv.Schema {
+	sch := &webcsv.Schema{
 		Version:    "1.0",
 		WithHeader: false,
 		Delimiter:  ",",
 	}
 
 	// The order of this SchemaColumn array must be matched
-	apiSchema.Columns = []webcsv.SchemaColumn{
+	sch.Columns = []webcsv.SchemaColumn{
 		{Name: "LastName", Type: "string", Length: 50},
 		{Name: "FirstName", Type: "string", Length: 50},
 		{Name: "MiddleName", Type: "string", Length: 50},
@@ -65,153 +99,116 @@ func main() {
 		{Name: "LastUpdated", Type: "datetime"},
 	}
 
-	log.Println("Listening at " + hp + "...")
-	log.Fatal(srv.ListenAndServe())
+	return sch
 }
 
-func basicCRUDHandler() http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-
-		// Handle POST and PUT and validate data
-		if r.Method == "POST" || r.Method == "PUT" {
-
-			b := func() []byte {
-				if r.Body != nil {
-					b, _ := ioutil.ReadAll(r.Body)
-					defer r.Body.Close()
-					return b
-				}
-				return []byte{}
-			}
-
-			// At this point, the handler could decide whether to validate a schema
-			// or directly parse the body of the data into CSV records
-			raw := strings.TrimSpace(r.Header.Get("Content-Schema"))
-			if strings.ToLower(raw) == "none" && raw == "" {
-				w.Write([]byte("ERROR,No valid schema found"))
-				return
-			}
+// loadSchema - reads the API schema from a file. The definitions of the columns could differ from the
+// default schema, but the columns must still be the person columns in the same order.
+func loadSchema(path string) (*webcsv.Schema, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
 
-			sch, err := webcsv.ParseSchema(raw)
-			if err != nil {
-				w.Write([]byte(fmt.Sprintf("ERROR,Parse: %v", err)))
-				return
-			}
+	sch, err := webcsv.ReadSchema(f)
+	if err != nil {
+		return nil, fmt.Errorf("schema %s: %v", path, err)
+	}
 
-			// API schema will validate the supplied schema.
-			if !apiSchema.IsValid(sch) {
-				w.Write([]byte(fmt.Sprintf("ERROR,Invalid schema")))
-				return
-			}
+	def := defaultSchema()
+	if len(sch.Columns) != len(def.Columns) {
+		return nil, fmt.Errorf("schema %s must have the %d columns of a person", path, len(def.Columns))
+	}
 
-			recs, err := sch.ValidateReturn(b())
-			if err != nil {
-				w.Write([]byte("ERROR,Data did not pass the validation against schema"))
-				return
-			}
+	for i := range def.Columns {
+		if !strings.EqualFold(sch.Columns[i].Name, def.Columns[i].Name) {
+			return nil, fmt.Errorf("schema %s has column %s where %s is expected", path, sch.Columns[i].Name, def.Columns[i].Name)
+		}
+	}
 
-			if r.Method == "POST" {
-				// Process storing the parsed data.
-				// This demo stores the data into a Person struct array for us to retrieve later
-				// A json.Unmarshal like deserialization could also be employed here.
-
-				if p == nil {
-					p = make([]Person, 0) // an existing list could have been uploaded
-				}
-
-				for _, rec := range recs {
-
-					// This should be aligned to the schema order
-					pitem := Person{
-						LastName:   rec[0],
-						FirstName:  rec[1],
-						MiddleName: rec[2],
-					}
-					pitem.Age, _ = strconv.Atoi(rec[3])
-					pitem.Height, _ = strconv.ParseFloat(rec[4], 64)
-					pitem.Weight, _ = strconv.ParseFloat(rec[5], 64)
-					pitem.Alive, _ = strconv.ParseBool(rec[6])
-					pitem.DateBorn, _ = time.Parse(time.RFC3339, rec[7])
-					pitem.LastUpdated, _ = time.Parse(time.RFC3339, rec[8])
-
-					p = append(p, pitem) // This is not optimal but this is just an example
-				}
-
-				w.Write([]byte("OK,Insert"))
-			}
+	return sch, nil
+}
 
-			if r.Method == "PUT" {
+// newRouter - returns the router of the API with the persons kept in the store. The data of POST and PUT
+// requests must come with the schema or its fingerprint, and it is validated by the API schema.
+func newRouter(store webcsvhttp.Store) http.Handler {
+	router := mux.NewRouter()
+	router.StrictSlash(true)
 
-				// Update could supply a query string to update the specified record
-				lname := r.URL.Query().Get("ln")
-				fname := r.URL.Query().Get("fn")
-				mname := r.URL.Query().Get("mn")
+	router.Use(corsMiddleware, requireSchema)
+	router.Path("/schema").Methods("GET", "HEAD", "OPTIONS").Handler(schemaHandler())
 
-				rec := recs[0] // Updates usuall just have one record
+	// preflight requests of any path are answered by corsMiddleware
+	router.Methods("OPTIONS").HandlerFunc(func(http.ResponseWriter, *http.Request) {})
 
-				for i := range p {
+	// A person is updated and deleted by the key columns, such as DELETE /?LastName=Doe&FirstName=John&MiddleName=X
+	webcsvhttp.RegisterCRUD(router, "/", apiSchema, store)
 
-					if p[i].LastName == lname && p[i].FirstName == fname && p[i].MiddleName == mname {
+	return router
+}
 
-						p[i].Age, _ = strconv.Atoi(rec[3])
-						p[i].Height, _ = strconv.ParseFloat(rec[4], 64)
-						p[i].Weight, _ = strconv.ParseFloat(rec[5], 64)
-						p[i].Alive, _ = strconv.ParseBool(rec[6])
-						p[i].DateBorn, _ = time.Parse(time.RFC3339, rec[7])
-						p[i].LastUpdated, _ = time.Parse(time.RFC3339, rec[8])
+// requireSchema - rejects POST and PUT requests that have neither the schema nor its fingerprint
+func requireSchema(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" || r.Method == "PUT" {
+			hash := strings.TrimSpace(r.Header.Get("Content-Schema-Hash"))
+			raw := strings.TrimSpace(r.Header.Get("Content-Schema"))
+			if hash == "" && (raw == "" || strings.ToLower(raw) == "none") {
+				webcsvhttp.WriteError(w, http.StatusBadRequest, "No valid schema found")
+				return
+			}
+		}
 
-						break
-					}
-				}
+		next.ServeHTTP(w, r)
+	})
+}
 
-				w.Write([]byte("OK,Update")) // Responding in CSV format
+// corsMiddleware - allows browsers of the allowed origins to call the API. Preflight OPTIONS requests
+// are answered here. The schema headers are exposed so that browser clients can read them.
+func corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
+		origin := r.Header.Get("Origin")
+		allowed := ""
+		for _, o := range corsOrigins {
+			if o == "*" || o == origin {
+				allowed = o
+				break
 			}
 		}
 
-		if r.Method == "GET" {
-
-			// Write schema on the header. It can check for request not to send the header to skip sending the header
-			w.Header().Set("Content-Schema", apiSchema.PrintSchema())
-
-			// The order of values should be returned as the schema specifies
-			cw := csv.NewWriter(w)
-			for _, prec := range p {
-				rec := []string{
-					prec.LastName,
-					prec.FirstName,
-					prec.MiddleName,
-				}
-				rec = append(rec, strconv.Itoa(prec.Age))
-				rec = append(rec, strconv.FormatFloat(prec.Height, 'f', apiSchema.Columns[4].Scale, 64))
-				rec = append(rec, strconv.FormatFloat(prec.Weight, 'f', apiSchema.Columns[5].Scale, 64))
-				rec = append(rec, strconv.FormatBool(prec.Alive))
-				rec = append(rec, prec.DateBorn.Format(time.RFC3339))
-				rec = append(rec, prec.LastUpdated.Format(time.RFC3339))
-
-				cw.Write(rec)
+		if origin != "" && allowed != "" {
+			w.Header().Set("Access-Control-Allow-Origin", allowed)
+			w.Header().Set("Access-Control-Expose-Headers", "Content-Schema, Content-Schema-Hash, X-Total-Count")
+			if allowed != "*" {
+				w.Header().Add("Vary", "Origin")
 			}
-
-			cw.Flush()
 		}
 
-		if r.Method == "DELETE" {
-
-			// Delete could supply a query string to delete the specified record
-			lname := r.URL.Query().Get("ln")
-			fname := r.URL.Query().Get("fn")
-			mname := r.URL.Query().Get("mn")
-
-			pcopy := p
-			p = make([]Person, 0)
-			for _, prec := range pcopy {
-				if prec.LastName == lname && prec.FirstName == fname && prec.MiddleName == mname {
-					continue
-				}
-				p = append(p, prec) // This is not optimal but this is just an example
+		if r.Method == "OPTIONS" {
+			if origin != "" && allowed != "" {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, POST, PUT, DELETE, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Schema, Content-Schema-Hash")
+				w.Header().Set("Access-Control-Max-Age", "600")
 			}
-
-			w.Write([]byte("OK,Delete")) // Responding in CSV format
+			w.WriteHeader(http.StatusNoContent)
+			return
 		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// schemaHandler - returns the schema headers of the API without the data
+func schemaHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		setSchemaHeaders(w, apiSchema)
 	})
 }
+
+// setSchemaHeaders - sets the schema and its fingerprint on the response headers
+func setSchemaHeaders(w http.ResponseWriter, sch *webcsv.Schema) {
+	w.Header().Set("Content-Schema", sch.PrintSchema())
+	w.Header().Set("Content-Schema-Hash", sch.Fingerprint())
+}
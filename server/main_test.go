@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	webcsvhttp "webcsv/lib/http"
+)
+
+const (
+	doeRecord = "Doe,John,X,30,1.5,60,true,2000-01-02,2020-01-01T00:00:00Z\n"
+	roeRecord = "Roe,Jane,Y,31,1.6,50,true,2000-01-02,2020-01-01T00:00:00Z\n"
+)
+
+// newTestHandler - sets up the API schema as main does and returns its router with an empty store
+func newTestHandler(t *testing.T) http.Handler {
+	apiSchema = defaultSchema()
+	apiSchema.PrimaryKey = []string{"LastName", "FirstName", "MiddleName"}
+
+	store, err := webcsvhttp.NewMemoryStore(apiSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return newRouter(store)
+}
+
+// serve - sends the request to the handler. The schema fingerprint is sent unless the headers have a schema.
+func serve(h http.Handler, method, target string, body io.Reader, hdr map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, body)
+	if _, ok := hdr["Content-Schema"]; !ok {
+		req.Header.Set("Content-Schema-Hash", apiSchema.Fingerprint())
+	}
+	for k, v := range hdr {
+		req.Header.Set(k, v)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestPostGzipBomb(t *testing.T) {
+	h := newTestHandler(t)
+
+	// a small compressed body that expands past the limit
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(doeRecord))
+	zw.Write(bytes.Repeat([]byte("\n"), int(webcsvhttp.MaxBodyBytes)))
+	zw.Close()
+
+	if int64(buf.Len()) >= webcsvhttp.MaxBodyBytes {
+		t.Fatalf("compressed body of %d bytes is not below the limit", buf.Len())
+	}
+
+	w := serve(h, "POST", "/", &buf, nil)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "exceeds maximum") {
+		t.Fatalf("got %d %q, want 400 for a body too large", w.Code, w.Body.String())
+	}
+}
+
+func TestPostGzip(t *testing.T) {
+	h := newTestHandler(t)
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(doeRecord + roeRecord))
+	zw.Close()
+
+	if w := serve(h, "POST", "/", &buf, nil); w.Code != http.StatusCreated {
+		t.Fatalf("got %d %q, want 201", w.Code, w.Body.String())
+	}
+
+	w := serve(h, "GET", "/", nil, nil)
+	if !strings.Contains(w.Body.String(), "Doe,John,X") || !strings.Contains(w.Body.String(), "Roe,Jane,Y") {
+		t.Fatalf("got %q, want both persons", w.Body.String())
+	}
+}
+
+func TestPostDuplicateKey(t *testing.T) {
+	h := newTestHandler(t)
+
+	// the key is enforced whether the client sends the schema or its fingerprint
+	for _, hdr := range []map[string]string{nil, {"Content-Schema": apiSchema.PrintSchema()}} {
+		w := serve(h, "POST", "/", strings.NewReader(doeRecord+doeRecord), hdr)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("headers %v: got %d %q, want 400 for a repeated person", hdr, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestGetJSONRepeatedPerson(t *testing.T) {
+	h := newTestHandler(t)
+
+	// plain inserts do not look for stored persons with the same key
+	for i := 0; i < 2; i++ {
+		if w := serve(h, "POST", "/", strings.NewReader(doeRecord), nil); w.Code != http.StatusCreated {
+			t.Fatalf("got %d %q, want 201", w.Code, w.Body.String())
+		}
+	}
+
+	w := serve(h, "GET", "/", nil, map[string]string{"Accept": "application/json"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d %q, want 200", w.Code, w.Body.String())
+	}
+
+	var maps []map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &maps); err != nil || len(maps) != 2 {
+		t.Fatalf("got %q, want 2 objects (%v)", w.Body.String(), err)
+	}
+
+	if maps[0]["Age"] != float64(30) || maps[0]["LastName"] != "Doe" {
+		t.Errorf("got %v, want the values of the person in their types", maps[0])
+	}
+}
+
+func TestPostWithoutSchema(t *testing.T) {
+	h := newTestHandler(t)
+
+	// an empty Content-Schema and no fingerprint
+	for _, raw := range []string{"", "none"} {
+		w := serve(h, "POST", "/", strings.NewReader(doeRecord), map[string]string{"Content-Schema": raw})
+		if w.Code != http.StatusBadRequest || w.Body.String() != "ERROR,No valid schema found" {
+			t.Errorf("%q: got %d %q, want 400 for no schema", raw, w.Code, w.Body.String())
+		}
+	}
+
+	w := serve(h, "POST", "/", strings.NewReader(" \n"), nil)
+	if w.Code != http.StatusBadRequest || w.Body.String() != "ERROR,No data found" {
+		t.Errorf("got %d %q, want 400 for an empty body", w.Code, w.Body.String())
+	}
+}
+
+// TestConcurrentRequests - run with go test -race to check the handler and its store are safe for concurrent use
+func TestConcurrentRequests(t *testing.T) {
+	h := newTestHandler(t)
+
+	var wg sync.WaitGroup
+	errs := make(chan string, 64)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			rec := fmt.Sprintf("Doe,John,%d,30,1.5,60,true,2000-01-02,2020-01-01T00:00:00Z\n", i)
+			if w := serve(h, "POST", "/", strings.NewReader(rec), nil); w.Code != http.StatusCreated {
+				errs <- fmt.Sprintf("POST %d: got %d %q", i, w.Code, w.Body.String())
+			}
+			if w := serve(h, "PUT", "/", strings.NewReader(rec), nil); w.Code != http.StatusOK {
+				errs <- fmt.Sprintf("PUT %d: got %d %q", i, w.Code, w.Body.String())
+			}
+			if w := serve(h, "GET", "/", nil, nil); w.Code != http.StatusOK {
+				errs <- fmt.Sprintf("GET %d: got %d %q", i, w.Code, w.Body.String())
+			}
+			if i%2 == 0 {
+				target := fmt.Sprintf("/?LastName=Doe&FirstName=John&MiddleName=%d", i)
+				if w := serve(h, "DELETE", target, nil, nil); w.Code != http.StatusOK {
+					errs <- fmt.Sprintf("DELETE %d: got %d %q", i, w.Code, w.Body.String())
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+
+	// only the persons with an odd middle name are left
+	w := serve(h, "GET", "/", nil, nil)
+	if n := strings.Count(w.Body.String(), "\n"); n != 8 {
+		t.Fatalf("got %d persons %q, want 8", n, w.Body.String())
+	}
+}